@@ -0,0 +1,237 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dirschema/internal/validate"
+)
+
+// Version is the baseline file format version written by Write.
+const Version = 1
+
+// Entry identifies a known violation. SchemaPath holds only the JSON Pointer
+// fragment, so baselines stay valid when the spec file moves. Name is the
+// entry a required or additionalProperties violation is about: the validator
+// reports every offending name of a directory in one item, and the baseline
+// records each name separately so that a new one is not hidden by the
+// others. Entries without a name match the whole item.
+type Entry struct {
+	InstancePath string `json:"instancePath"`
+	Keyword      string `json:"keyword"`
+	SchemaPath   string `json:"schemaPath"`
+	Name         string `json:"name,omitempty"`
+}
+
+// File is the on-disk baseline document.
+type File struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// FromResult records every item in result as a baseline entry.
+func FromResult(result validate.Result) File {
+	seen := map[Entry]struct{}{}
+	entries := make([]Entry, 0, len(result.Errors))
+	for _, item := range result.Errors {
+		for _, entry := range entriesFor(item) {
+			if _, ok := seen[entry]; ok {
+				continue
+			}
+			seen[entry] = struct{}{}
+			entries = append(entries, entry)
+		}
+	}
+	sortEntries(entries)
+	return File{Version: Version, Entries: entries}
+}
+
+// Load reads a baseline file written by Write.
+func Load(path string) (File, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, err
+	}
+	var f File
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return File{}, fmt.Errorf("invalid baseline: %w", err)
+	}
+	if f.Version != Version {
+		return File{}, fmt.Errorf("unsupported baseline version %d", f.Version)
+	}
+	return f, nil
+}
+
+// Write stores f as indented JSON so baselines diff cleanly in review.
+func Write(path string, f File) error {
+	encoded, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(encoded, '\n'), 0o644)
+}
+
+// Apply drops the items of result that the baseline already records and
+// returns the baseline entries that no longer occur (fixed violations that
+// can be removed from the file) along with the number of suppressed items.
+// An item that names several entries is kept with only the names the
+// baseline does not record.
+func Apply(result *validate.Result, f File) (fixed []Entry, suppressed int) {
+	known := make(map[Entry]bool, len(f.Entries))
+	for _, entry := range f.Entries {
+		known[entry] = false
+	}
+
+	drop := make([]bool, len(result.Errors))
+	for i, item := range result.Errors {
+		whole := entryFor(item)
+		if _, ok := known[whole]; ok {
+			known[whole] = true
+			drop[i] = true
+			continue
+		}
+		names := itemNames(item)
+		var fresh []string
+		for _, name := range names {
+			entry := whole
+			entry.Name = name
+			if _, ok := known[entry]; ok {
+				known[entry] = true
+				continue
+			}
+			fresh = append(fresh, name)
+		}
+		switch {
+		case len(names) == 0 || len(fresh) == len(names):
+		case len(fresh) == 0:
+			drop[i] = true
+		default:
+			result.Errors[i].Message = formatNames(item.Keyword, fresh)
+			result.Errors[i].Suggestions = suggestionsFor(item.Suggestions, fresh)
+		}
+	}
+	// Retain visits the items in order.
+	i := 0
+	result.Retain(func(validate.Item) bool {
+		keep := !drop[i]
+		i++
+		if !keep {
+			suppressed++
+		}
+		return keep
+	})
+
+	for _, entry := range f.Entries {
+		if !known[entry] {
+			fixed = append(fixed, entry)
+		}
+	}
+	sortEntries(fixed)
+	return fixed, suppressed
+}
+
+func entryFor(item validate.Item) Entry {
+	fragment := item.SchemaPath
+	if idx := strings.Index(fragment, "#"); idx >= 0 {
+		fragment = fragment[idx+1:]
+	}
+	return Entry{
+		InstancePath: item.InstancePath,
+		Keyword:      item.Keyword,
+		SchemaPath:   fragment,
+	}
+}
+
+// entriesFor returns the baseline entries of an item: one per named entry
+// for aggregated items, otherwise one for the whole item.
+func entriesFor(item validate.Item) []Entry {
+	entry := entryFor(item)
+	names := itemNames(item)
+	if len(names) == 0 {
+		return []Entry{entry}
+	}
+	entries := make([]Entry, len(names))
+	for i, name := range names {
+		entries[i] = entry
+		entries[i].Name = name
+	}
+	return entries
+}
+
+// quotedName matches a name as the validator quotes it in messages.
+var quotedName = regexp.MustCompile(`'((?:[^'\\]|\\.)*)'`)
+
+// itemNames returns the entry names a required or additionalProperties item
+// reports, in message order.
+func itemNames(item validate.Item) []string {
+	if item.Keyword != "required" && item.Keyword != "additionalProperties" {
+		return nil
+	}
+	var names []string
+	for _, match := range quotedName.FindAllStringSubmatch(item.Message, -1) {
+		names = append(names, unquoteName(match[1]))
+	}
+	return names
+}
+
+// unquoteName reverses the validator's quoting: Go string escapes, with
+// single quotes escaped and double quotes not.
+func unquoteName(quoted string) string {
+	s := strings.ReplaceAll(quoted, `\'`, `'`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	if name, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return name
+	}
+	return quoted
+}
+
+// formatNames rewrites an aggregated item's message for a subset of its
+// names, the way the validator words it.
+func formatNames(keyword string, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		s := strconv.Quote(name)
+		s = strings.ReplaceAll(s, `\"`, `"`)
+		s = strings.ReplaceAll(s, `'`, `\'`)
+		quoted[i] = "'" + s[1:len(s)-1] + "'"
+	}
+	list := strings.Join(quoted, ", ")
+	if keyword == "additionalProperties" {
+		return fmt.Sprintf("additionalProperties %s not allowed", list)
+	}
+	return fmt.Sprintf("missing properties: %s", list)
+}
+
+// suggestionsFor keeps the suggestions for the given missing names.
+func suggestionsFor(suggestions []validate.Suggestion, names []string) []validate.Suggestion {
+	var kept []validate.Suggestion
+	for _, s := range suggestions {
+		for _, name := range names {
+			if s.Missing == name {
+				kept = append(kept, s)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].InstancePath != entries[j].InstancePath {
+			return entries[i].InstancePath < entries[j].InstancePath
+		}
+		if entries[i].SchemaPath != entries[j].SchemaPath {
+			return entries[i].SchemaPath < entries[j].SchemaPath
+		}
+		if entries[i].Keyword != entries[j].Keyword {
+			return entries[i].Keyword < entries[j].Keyword
+		}
+		return entries[i].Name < entries[j].Name
+	})
+}
@@ -0,0 +1,103 @@
+package baseline
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"dirschema/internal/validate"
+)
+
+func TestFromResultStripsSchemaURI(t *testing.T) {
+	res := validate.Result{Errors: []validate.Item{
+		{InstancePath: "/b", Keyword: "const", SchemaPath: "file:///tmp/schema.json#/properties/b/const"},
+		{InstancePath: "", Keyword: "required", SchemaPath: "file:///tmp/schema.json#/required"},
+		{InstancePath: "", Keyword: "required", SchemaPath: "file:///tmp/schema.json#/required"},
+	}}
+
+	got := FromResult(res)
+	want := File{Version: Version, Entries: []Entry{
+		{InstancePath: "", Keyword: "required", SchemaPath: "/required"},
+		{InstancePath: "/b", Keyword: "const", SchemaPath: "/properties/b/const"},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FromResult = %#v, want %#v", got, want)
+	}
+}
+
+func TestApplyReportsNewAndFixed(t *testing.T) {
+	file := File{Version: Version, Entries: []Entry{
+		{InstancePath: "", Keyword: "required", SchemaPath: "/required"},
+		{InstancePath: "/gone", Keyword: "const", SchemaPath: "/properties/gone/const"},
+	}}
+	res := validate.Result{Errors: []validate.Item{
+		{InstancePath: "", Keyword: "required", SchemaPath: "file:///elsewhere/schema.json#/required", Severity: validate.SeverityError},
+		{InstancePath: "/new", Keyword: "const", SchemaPath: "#/properties/new/const", Severity: validate.SeverityError},
+	}}
+
+	fixed, suppressed := Apply(&res, file)
+	if suppressed != 1 {
+		t.Fatalf("suppressed = %d, want 1", suppressed)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].InstancePath != "/new" || res.Valid {
+		t.Fatalf("unexpected remaining result: %+v", res)
+	}
+	wantFixed := []Entry{{InstancePath: "/gone", Keyword: "const", SchemaPath: "/properties/gone/const"}}
+	if !reflect.DeepEqual(fixed, wantFixed) {
+		t.Fatalf("fixed = %#v, want %#v", fixed, wantFixed)
+	}
+}
+
+func TestBaselineRecordsAggregatedNamesSeparately(t *testing.T) {
+	recorded := validate.Result{Errors: []validate.Item{
+		{Keyword: "required", SchemaPath: "#/required", Message: "missing properties: 'a.txt', 'it\\'s.md'"},
+	}}
+	file := FromResult(recorded)
+	want := []Entry{
+		{Keyword: "required", SchemaPath: "/required", Name: "a.txt"},
+		{Keyword: "required", SchemaPath: "/required", Name: "it's.md"},
+	}
+	if !reflect.DeepEqual(file.Entries, want) {
+		t.Fatalf("entries = %#v, want %#v", file.Entries, want)
+	}
+
+	// c.txt is newly missing next to the baselined names; it is still
+	// reported, on its own.
+	res := validate.Result{Errors: []validate.Item{
+		{Keyword: "required", SchemaPath: "#/required", Message: "missing properties: 'a.txt', 'c.txt', 'it\\'s.md'", Severity: validate.SeverityError},
+	}}
+	fixed, suppressed := Apply(&res, file)
+	if suppressed != 0 || len(fixed) != 0 {
+		t.Fatalf("suppressed = %d, fixed = %v", suppressed, fixed)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Message != "missing properties: 'c.txt'" || res.Valid {
+		t.Fatalf("unexpected remaining result: %+v", res)
+	}
+
+	// Once c.txt exists again, the item is fully baselined.
+	res = validate.Result{Errors: []validate.Item{
+		{Keyword: "required", SchemaPath: "#/required", Message: "missing properties: 'a.txt'", Severity: validate.SeverityError},
+	}}
+	fixed, suppressed = Apply(&res, file)
+	if suppressed != 1 || len(res.Errors) != 0 || !res.Valid {
+		t.Fatalf("expected a.txt suppressed, got %+v", res)
+	}
+	if len(fixed) != 1 || fixed[0].Name != "it's.md" {
+		t.Fatalf("fixed = %#v", fixed)
+	}
+}
+
+func TestWriteLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	file := File{Version: Version, Entries: []Entry{{InstancePath: "/a", Keyword: "type", SchemaPath: "/properties/a/type"}}}
+	if err := Write(path, file); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(got, file) {
+		t.Fatalf("round trip mismatch: %#v", got)
+	}
+}
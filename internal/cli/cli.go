@@ -1,19 +1,26 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"dirschema/internal/baseline"
 	"dirschema/internal/expand"
 	"dirschema/internal/fswalk"
 	"dirschema/internal/hydrate"
 	"dirschema/internal/instance"
+	"dirschema/internal/render"
 	"dirschema/internal/report"
+	"dirschema/internal/source"
 	"dirschema/internal/spec"
+	"dirschema/internal/textdiff"
 	"dirschema/internal/validate"
 )
 
@@ -21,6 +28,9 @@ const (
 	ExitSuccess     = 0
 	ExitValidation  = 1
 	ExitConfigError = 2
+	// ExitConflict means hydrate found existing entries that block a clean
+	// result.
+	ExitConflict = 3
 )
 
 func Run(args []string, stdout, stderr io.Writer) int {
@@ -34,10 +44,26 @@ func Run(args []string, stdout, stderr io.Writer) int {
 		return runExpand(args[1:], stdout, stderr)
 	case "export":
 		return runExport(args[1:], stdout, stderr)
+	case "compact":
+		return runCompact(args[1:], stdout, stderr)
+	case "fmt":
+		return runFmt(args[1:], stdout, stderr)
+	case "lint":
+		return runLint(args[1:], stdout, stderr)
+	case "diff":
+		return runDiff(args[1:], stdout, stderr)
+	case "treediff":
+		return runTreediff(args[1:], stdout, stderr)
+	case "coverage":
+		return runCoverage(args[1:], stdout, stderr)
 	case "validate":
 		return runValidate(args[1:], stdout, stderr)
 	case "hydrate":
 		return runHydrate(args[1:], stdout, stderr)
+	case "prune":
+		return runPrune(args[1:], stdout, stderr)
+	case "refactor":
+		return runRefactor(args[1:], stdout, stderr)
 	case "version", "--version":
 		fmt.Fprintln(stdout, Version)
 		return ExitSuccess
@@ -52,6 +78,9 @@ func Run(args []string, stdout, stderr io.Writer) int {
 func runExpand(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("expand", flag.ContinueOnError)
 	fs.SetOutput(stderr)
+	formatFlag := fs.String("format", spec.FormatJSON, "output format (json|yaml)")
+	indent := fs.Int("indent", 0, "spaces per indentation level (0: compact JSON, 2 for YAML)")
+	checkPath := fs.String("check", "", "exit 1 if FILE differs from the expansion instead of printing it")
 	if err := fs.Parse(args); err != nil {
 		return ExitConfigError
 	}
@@ -60,6 +89,22 @@ func runExpand(args []string, stdout, stderr io.Writer) int {
 		fmt.Fprintln(stderr, "expand requires a single spec path")
 		return ExitConfigError
 	}
+	// A checked file's extension picks the format unless one is given.
+	format := *formatFlag
+	if *checkPath != "" && !flagSet(fs, "format") {
+		switch strings.ToLower(filepath.Ext(*checkPath)) {
+		case ".yaml", ".yml":
+			format = spec.FormatYAML
+		}
+	}
+	if format != spec.FormatJSON && format != spec.FormatYAML {
+		fmt.Fprintln(stderr, "invalid --format (must be json or yaml)")
+		return ExitConfigError
+	}
+	if *indent < 0 {
+		fmt.Fprintln(stderr, "invalid --indent (must not be negative)")
+		return ExitConfigError
+	}
 
 	specPath := fs.Arg(0)
 	loaded, err := spec.Load(specPath)
@@ -100,16 +145,27 @@ func runExpand(args []string, stdout, stderr io.Writer) int {
 		return ExitConfigError
 	}
 
-	encoded, err := json.Marshal(output)
+	encoded, err := spec.Marshal(expand.Canonicalize(output), format, *indent)
 	if err != nil {
 		fmt.Fprintf(stderr, "failed to encode schema: %v\n", err)
 		return ExitConfigError
 	}
-	if _, err := stdout.Write(encoded); err != nil {
-		fmt.Fprintf(stderr, "failed to write output: %v\n", err)
-		return ExitConfigError
+
+	if *checkPath != "" {
+		committed, err := os.ReadFile(*checkPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to read %s: %v\n", *checkPath, err)
+			return ExitConfigError
+		}
+		if bytes.Equal(committed, encoded) {
+			return ExitSuccess
+		}
+		diff, _ := textdiff.Unified(*checkPath, "expanded", string(committed), string(encoded), 200)
+		fmt.Fprintf(stderr, "%s is stale; regenerate it with dirschema expand\n%s\n", *checkPath, diff)
+		return ExitValidation
 	}
-	if _, err := stdout.Write([]byte("\n")); err != nil {
+
+	if _, err := stdout.Write(encoded); err != nil {
 		fmt.Fprintf(stderr, "failed to write output: %v\n", err)
 		return ExitConfigError
 	}
@@ -117,20 +173,54 @@ func runExpand(args []string, stdout, stderr io.Writer) int {
 	return ExitSuccess
 }
 
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func runValidate(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	rootFlag := fs.String("root", "", "root directory")
 	formatFlag := fs.String("format", "text", "output format (text|json)")
 	printInstance := fs.Bool("print-instance", false, "print derived instance JSON")
+	warningsAsErrors := fs.Bool("warnings-as-errors", false, "treat warning-severity violations as errors")
+	baselinePath := fs.String("baseline", "", "suppress violations recorded in this baseline file")
+	writeBaseline := fs.String("write-baseline", "", "record current violations to this baseline file")
+	maxErrors := fs.Int("max-errors", 0, "report at most N violations (0 = unlimited)")
+	failFast := fs.Bool("fail-fast", false, "stop at the first violation, skipping the full walk when the root is already invalid")
+	var varFlags stringList
+	fs.Var(&varFlags, "var", "template variable name=value (repeatable)")
+	varsFile := fs.String("vars", "", "template variables file (yaml|json|jsonnet)")
 	if err := fs.Parse(args); err != nil {
 		return ExitConfigError
 	}
+	if *maxErrors < 0 {
+		fmt.Fprintln(stderr, "--max-errors must not be negative")
+		return ExitConfigError
+	}
 
 	if fs.NArg() != 1 {
 		fmt.Fprintln(stderr, "validate requires a single spec path")
 		return ExitConfigError
 	}
+	if *baselinePath != "" && *writeBaseline != "" {
+		fmt.Fprintln(stderr, "--baseline cannot be used with --write-baseline")
+		return ExitConfigError
+	}
+	if *failFast && (*baselinePath != "" || *writeBaseline != "") {
+		// A fail-fast result is partial: a baseline written from it would
+		// hide every violation the walk skipped, and one applied to it
+		// would report entries below unwalked directories as fixed.
+		fmt.Fprintln(stderr, "--fail-fast cannot be used with --baseline or --write-baseline")
+		return ExitConfigError
+	}
 	if *formatFlag != "text" && *formatFlag != "json" {
 		fmt.Fprintln(stderr, "invalid --format (must be text or json)")
 		return ExitConfigError
@@ -146,6 +236,16 @@ func runValidate(args []string, stdout, stderr io.Writer) int {
 		fmt.Fprintf(stderr, "%v\n", err)
 		return ExitConfigError
 	}
+	schema, err = renderSchema(schema, varFlags, *varsFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+	schema, err = resolveSources(schema, specPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
 
 	root := *rootFlag
 	if root == "" {
@@ -161,11 +261,44 @@ func runValidate(args []string, stdout, stderr io.Writer) int {
 		return ExitConfigError
 	}
 
-	walkOpts := instance.ScanAttributes(schema)
-	inst, err := fswalk.WalkWithSchema(root, walkOpts, schema)
-	if err != nil {
-		fmt.Fprintf(stderr, "failed to walk filesystem: %v\n", err)
-		return ExitConfigError
+	var inst map[string]any
+	var result validate.Result
+	stoppedEarly := false
+	if *failFast {
+		// Check the root's own constraints against a one-level walk first,
+		// so a root missing required entries fails without walking the tree.
+		shallow := validate.ShallowSchema(schema)
+		inst, err = fswalk.WalkWithSchema(root, fswalk.Options{SymlinkPolicy: fswalk.SymlinkRecord, MaxDepth: 1}, shallow)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to walk filesystem: %v\n", err)
+			return ExitConfigError
+		}
+		result, err = validate.Validate(shallow, inst)
+		if err != nil {
+			fmt.Fprintf(stderr, "validation failed: %v\n", err)
+			return ExitConfigError
+		}
+		if *warningsAsErrors {
+			result.PromoteWarnings()
+		}
+		stoppedEarly = !result.Valid
+	}
+
+	if !stoppedEarly {
+		walkOpts := instance.ScanAttributes(schema)
+		inst, err = fswalk.WalkWithSchema(root, walkOpts, schema)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to walk filesystem: %v\n", err)
+			return ExitConfigError
+		}
+		result, err = validate.Validate(schema, inst)
+		if err != nil {
+			fmt.Fprintf(stderr, "validation failed: %v\n", err)
+			return ExitConfigError
+		}
+		if *warningsAsErrors {
+			result.PromoteWarnings()
+		}
 	}
 
 	if *printInstance {
@@ -175,18 +308,44 @@ func runValidate(args []string, stdout, stderr io.Writer) int {
 		}
 	}
 
-	result, err := validate.Validate(schema, inst)
-	if err != nil {
-		fmt.Fprintf(stderr, "validation failed: %v\n", err)
-		return ExitConfigError
+	if *writeBaseline != "" {
+		file := baseline.FromResult(result)
+		if err := baseline.Write(*writeBaseline, file); err != nil {
+			fmt.Fprintf(stderr, "failed to write baseline: %v\n", err)
+			return ExitConfigError
+		}
+		fmt.Fprintf(stderr, "wrote %d baseline entries to %s\n", len(file.Entries), *writeBaseline)
+		return ExitSuccess
 	}
 
-	if result.Valid {
+	var fixed []baseline.Entry
+	suppressed := 0
+	if *baselinePath != "" {
+		file, err := baseline.Load(*baselinePath)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to load baseline: %v\n", err)
+			return ExitConfigError
+		}
+		fixed, suppressed = baseline.Apply(&result, file)
+	}
+
+	limit := *maxErrors
+	if *failFast && (limit == 0 || limit > 1) {
+		limit = 1
+	}
+	result.Limit(limit)
+
+	if len(result.Errors) == 0 && len(fixed) == 0 {
 		return ExitSuccess
 	}
 
 	if *formatFlag == "json" {
-		payload, err := report.FormatJSON(result)
+		var payload []byte
+		if *baselinePath != "" {
+			payload, err = report.FormatBaselineJSON(result, fixed, suppressed)
+		} else {
+			payload, err = report.FormatJSON(result)
+		}
 		if err != nil {
 			fmt.Fprintf(stderr, "failed to encode report: %v\n", err)
 			return ExitConfigError
@@ -201,6 +360,12 @@ func runValidate(args []string, stdout, stderr io.Writer) int {
 		}
 	} else {
 		text := report.FormatText(result)
+		if baselineText := report.FormatBaselineText(fixed, suppressed); baselineText != "" {
+			if text != "" {
+				text += "\n"
+			}
+			text += baselineText
+		}
 		if text != "" {
 			if _, err := stderr.Write([]byte(text + "\n")); err != nil {
 				fmt.Fprintf(stderr, "failed to write report: %v\n", err)
@@ -209,6 +374,9 @@ func runValidate(args []string, stdout, stderr io.Writer) int {
 		}
 	}
 
+	if result.Valid {
+		return ExitSuccess
+	}
 	return ExitValidation
 }
 
@@ -217,6 +385,12 @@ func runExport(args []string, stdout, stderr io.Writer) int {
 	fs.SetOutput(stderr)
 	rootFlag := fs.String("root", "", "root directory")
 	followSymlinks := fs.Bool("follow-symlinks", false, "follow symlinks instead of recording them")
+	withFlag := fs.String("with", "", "file attributes to record (comma-separated: sha256,size,mode,content)")
+	inferGlobs := fs.Bool("infer-globs", false, "collapse similar sibling files and directories into glob entries")
+	globMin := fs.Int("glob-min", expand.DefaultInferOptions.MinFiles, "sibling files that must share a pattern to become a glob (with --infer-globs)")
+	dirMin := fs.Int("dir-min", expand.DefaultInferOptions.MinDirs, "sibling directories that must share a structure to become */ (with --infer-globs)")
+	formatFlag := fs.String("format", spec.FormatJSON, "output format (json|yaml|jsonnet)")
+	shapeFlag := fs.String("shape", "list", "output shape (list|dict|schema)")
 	if err := fs.Parse(args); err != nil {
 		return ExitConfigError
 	}
@@ -224,9 +398,23 @@ func runExport(args []string, stdout, stderr io.Writer) int {
 		fmt.Fprintln(stderr, "export does not accept positional arguments")
 		return ExitConfigError
 	}
+	switch *formatFlag {
+	case spec.FormatJSON, spec.FormatYAML, spec.FormatJsonnet:
+	default:
+		fmt.Fprintln(stderr, "invalid --format (must be json, yaml or jsonnet)")
+		return ExitConfigError
+	}
+	if *shapeFlag != "list" && *shapeFlag != "dict" && *shapeFlag != "schema" {
+		fmt.Fprintln(stderr, "invalid --shape (must be list, dict or schema)")
+		return ExitConfigError
+	}
+	opts, err := exportOptions(*withFlag)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid --with: %v\n", err)
+		return ExitConfigError
+	}
 
 	root := *rootFlag
-	var err error
 	if root == "" {
 		root, err = os.Getwd()
 		if err != nil {
@@ -240,52 +428,180 @@ func runExport(args []string, stdout, stderr io.Writer) int {
 		return ExitConfigError
 	}
 
-	policy := fswalk.SymlinkRecord
+	opts.SymlinkPolicy = fswalk.SymlinkRecord
 	if *followSymlinks {
-		policy = fswalk.SymlinkFollow
+		opts.SymlinkPolicy = fswalk.SymlinkFollow
 	}
-	inst, err := fswalk.Walk(root, fswalk.Options{SymlinkPolicy: policy})
+	inst, err := fswalk.Walk(root, opts)
 	if err != nil {
 		fmt.Fprintf(stderr, "failed to walk filesystem: %v\n", err)
 		return ExitConfigError
 	}
 
-	list := expand.FormatListDSL(inst)
-	if err := writeJSON(stdout, list); err != nil {
+	if *inferGlobs {
+		inst = expand.InferGlobs(inst, expand.InferOptions{MinFiles: *globMin, MinDirs: *dirMin})
+	}
+
+	var out any
+	switch *shapeFlag {
+	case "list":
+		out = expand.FormatListDSL(inst)
+	case "dict":
+		out = inst
+	case "schema":
+		out, err = exportSchema(inst)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to expand export: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	encoded, err := spec.Marshal(out, *formatFlag, 2)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to encode export: %v\n", err)
+		return ExitConfigError
+	}
+	if _, err := stdout.Write(encoded); err != nil {
 		fmt.Fprintf(stderr, "failed to write export: %v\n", err)
 		return ExitConfigError
 	}
 	return ExitSuccess
 }
 
+// exportSchema expands an exported instance into the full JSON Schema. The
+// instance goes through JSON first so its values have the types a loaded
+// spec would have.
+func exportSchema(inst map[string]any) (map[string]any, error) {
+	raw, err := json.Marshal(inst)
+	if err != nil {
+		return nil, err
+	}
+	root, err := decodeRoot(raw)
+	if err != nil {
+		return nil, err
+	}
+	return expand.ExpandDSL(root)
+}
+
+// exportOptions turns the --with list into walker options.
+func exportOptions(with string) (fswalk.Options, error) {
+	var opts fswalk.Options
+	if with == "" {
+		return opts, nil
+	}
+	for _, attr := range strings.Split(with, ",") {
+		switch strings.TrimSpace(attr) {
+		case "sha256":
+			opts.IncludeSHA256 = true
+		case "size":
+			opts.IncludeSize = true
+		case "mode":
+			opts.IncludeMode = true
+		case "content":
+			opts.IncludeContent = true
+			opts.MaxContentBytes = instance.DefaultMaxContentBytes
+		default:
+			return opts, fmt.Errorf("unknown attribute %q (want sha256, size, mode or content)", attr)
+		}
+	}
+	return opts, nil
+}
+
 func runHydrate(args []string, stdout, stderr io.Writer) int {
 	fs := flag.NewFlagSet("hydrate", flag.ContinueOnError)
 	fs.SetOutput(stderr)
 	rootFlag := fs.String("root", "", "root directory")
 	formatFlag := fs.String("format", "text", "output format (text|json)")
 	dryRun := fs.Bool("dry-run", false, "print planned operations without applying")
+	update := fs.Bool("update", false, "overwrite files whose content differs and fix symlink targets")
+	backupDir := fs.String("backup-dir", "", "keep previous versions of updated entries in DIR (requires --update)")
+	journalPath := fs.String("journal", "", "record created and updated paths in FILE for --undo")
+	undoPath := fs.String("undo", "", "revert the hydration recorded in journal FILE")
+	outDir := fs.String("out", "", "write the created entries below DIR instead of the root")
+	outArchive := fs.String("out-archive", "", "write the created entries to a .tar.gz FILE instead of the root")
+	interactive := fs.Bool("interactive", false, "review each planned op before applying it")
+	recordPath := fs.String("record", "", "write the plan to be applied to FILE for --replay")
+	replayPath := fs.String("replay", "", "apply the plan recorded in FILE instead of a spec")
+	var varFlags stringList
+	fs.Var(&varFlags, "var", "template variable name=value (repeatable)")
+	varsFile := fs.String("vars", "", "template variables file (yaml|json|jsonnet)")
 	if err := fs.Parse(args); err != nil {
 		return ExitConfigError
 	}
 
-	if fs.NArg() != 1 {
+	if *undoPath != "" {
+		if fs.NArg() != 0 {
+			fmt.Fprintln(stderr, "--undo does not take a spec path")
+			return ExitConfigError
+		}
+		return undoHydrate(*undoPath, stderr)
+	}
+
+	if *replayPath != "" && fs.NArg() > 1 {
+		fmt.Fprintln(stderr, "--replay takes at most one spec path")
+		return ExitConfigError
+	}
+	if *replayPath == "" && fs.NArg() != 1 {
 		fmt.Fprintln(stderr, "hydrate requires a single spec path")
 		return ExitConfigError
 	}
+	if *interactive && fs.Arg(0) == "-" {
+		fmt.Fprintln(stderr, "--interactive reads answers from stdin; pass the spec as a file")
+		return ExitConfigError
+	}
 	if *formatFlag != "text" && *formatFlag != "json" {
 		fmt.Fprintln(stderr, "invalid --format (must be text or json)")
 		return ExitConfigError
 	}
 
-	specPath := fs.Arg(0)
-	schema, err := loadSchema(specPath)
-	if err != nil {
-		fmt.Fprintf(stderr, "%v\n", err)
+	if *backupDir != "" && !*update {
+		fmt.Fprintln(stderr, "--backup-dir requires --update")
 		return ExitConfigError
 	}
+	elsewhere := *outDir != "" || *outArchive != ""
+	if *outDir != "" && *outArchive != "" {
+		fmt.Fprintln(stderr, "--out and --out-archive are mutually exclusive")
+		return ExitConfigError
+	}
+	if elsewhere && (*update || *journalPath != "") {
+		fmt.Fprintln(stderr, "--out and --out-archive cannot be combined with --update or --journal")
+		return ExitConfigError
+	}
+
+	// A replayed plan only reads the spec for copy sources, so the tree is
+	// not validated afterwards.
+	var schema map[string]any
+	var err error
+	if fs.NArg() == 1 {
+		specPath := fs.Arg(0)
+		schema, err = loadSchema(specPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return ExitConfigError
+		}
+		schema, err = renderSchema(schema, varFlags, *varsFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return ExitConfigError
+		}
+		schema, err = resolveSources(schema, specPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return ExitConfigError
+		}
+	}
 
 	root := *rootFlag
-	if root == "" {
+	switch {
+	case root == "" && elsewhere:
+		// Without an explicit root, emit the whole skeleton by planning
+		// against an empty tree.
+		root, err = os.MkdirTemp("", "dirschema-hydrate-")
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to create empty root: %v\n", err)
+			return ExitConfigError
+		}
+		defer os.RemoveAll(root)
+	case root == "":
 		root, err = os.Getwd()
 		if err != nil {
 			fmt.Fprintf(stderr, "failed to get working directory: %v\n", err)
@@ -298,10 +614,46 @@ func runHydrate(args []string, stdout, stderr io.Writer) int {
 		return ExitConfigError
 	}
 
-	plan, err := hydrate.BuildPlan(schema, root)
-	if err != nil {
-		fmt.Fprintf(stderr, "failed to build hydrate plan: %v\n", err)
-		return ExitConfigError
+	var plan hydrate.Plan
+	if *replayPath != "" {
+		plan, err = hydrate.LoadPlan(*replayPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to load plan: %v\n", err)
+			return ExitConfigError
+		}
+		plan, err = plan.Rebase(root, schema)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to replay plan: %v\n", err)
+			return ExitConfigError
+		}
+		schema = nil
+	} else {
+		plan, err = hydrate.BuildPlan(schema, root)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to build hydrate plan: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	if *update {
+		plan = plan.WithUpdates()
+	}
+	if *interactive {
+		// Prompts go to stderr so stdout still carries only the plan.
+		plan, err = reviewPlan(plan, stdin, stderr)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to review plan: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	if *recordPath != "" {
+		payload, err := hydrate.FormatOpsJSON(plan)
+		if err == nil {
+			err = os.WriteFile(*recordPath, append(payload, '\n'), 0o644)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to record plan: %v\n", err)
+			return ExitConfigError
+		}
 	}
 
 	// Text mode: always print ops to stdout
@@ -315,8 +667,29 @@ func runHydrate(args []string, stdout, stderr io.Writer) int {
 		}
 	}
 
-	// Dry-run: just print plan and exit
-	if *dryRun {
+	switch {
+	case *dryRun:
+	case elsewhere:
+		if err := hydrateElsewhere(plan, *outDir, *outArchive); err != nil {
+			fmt.Fprintf(stderr, "failed to write hydrate output: %v\n", err)
+			return ExitConfigError
+		}
+	default:
+		var journal hydrate.Journal
+		if err := hydrate.Apply(plan, hydrate.ApplyOptions{BackupDir: *backupDir, Journal: &journal}); err != nil {
+			fmt.Fprintf(stderr, "failed to apply hydrate plan (changes rolled back): %v\n", err)
+			return ExitConfigError
+		}
+		if *journalPath != "" {
+			if err := hydrate.WriteJournal(*journalPath, journal); err != nil {
+				fmt.Fprintf(stderr, "failed to write journal: %v\n", err)
+				return ExitConfigError
+			}
+		}
+	}
+
+	// Nothing to validate: just print plan and exit
+	if *dryRun || elsewhere || schema == nil {
 		if *formatFlag == "json" {
 			payload, err := hydrate.FormatOpsJSON(plan)
 			if err != nil {
@@ -332,14 +705,39 @@ func runHydrate(args []string, stdout, stderr io.Writer) int {
 				return ExitConfigError
 			}
 		}
+		if len(plan.Conflicts()) > 0 {
+			return ExitConflict
+		}
 		return ExitSuccess
 	}
 
-	if err := hydrate.Apply(plan, hydrate.ApplyOptions{}); err != nil {
-		fmt.Fprintf(stderr, "failed to apply hydrate plan: %v\n", err)
-		return ExitConfigError
+	return validateApplied(schema, root, plan, *formatFlag, stdout, stderr)
+}
+
+// hydrateElsewhere applies plan into outDir or a new archive at outArchive.
+// A partially written archive is removed on failure.
+func hydrateElsewhere(plan hydrate.Plan, outDir, outArchive string) error {
+	if outDir != "" {
+		return hydrate.Apply(plan, hydrate.ApplyOptions{Sink: hydrate.NewDirSink(outDir)})
 	}
 
+	f, err := os.Create(outArchive)
+	if err != nil {
+		return err
+	}
+	sink := hydrate.NewArchiveSink(f)
+	err = hydrate.Apply(plan, hydrate.ApplyOptions{Sink: sink})
+	err = errors.Join(err, sink.Close(), f.Close())
+	if err != nil {
+		os.Remove(outArchive)
+	}
+	return err
+}
+
+// validateApplied validates the tree after a plan was applied and reports
+// the plan together with the result. Conflicts left in the plan take
+// precedence over validation failures in the exit code.
+func validateApplied(schema map[string]any, root string, plan hydrate.Plan, format string, stdout, stderr io.Writer) int {
 	walkOpts := instance.ScanAttributes(schema)
 	inst, err := fswalk.WalkWithSchema(root, walkOpts, schema)
 	if err != nil {
@@ -352,7 +750,7 @@ func runHydrate(args []string, stdout, stderr io.Writer) int {
 		return ExitConfigError
 	}
 
-	if *formatFlag == "json" {
+	if format == "json" {
 		payload, err := report.FormatHydrateJSON(plan, result)
 		if err != nil {
 			fmt.Fprintf(stderr, "failed to encode report: %v\n", err)
@@ -366,7 +764,7 @@ func runHydrate(args []string, stdout, stderr io.Writer) int {
 			fmt.Fprintf(stderr, "failed to write report: %v\n", err)
 			return ExitConfigError
 		}
-	} else if !result.Valid {
+	} else if len(result.Errors) > 0 {
 		text := report.FormatText(result)
 		if text != "" {
 			if _, err := stderr.Write([]byte(text + "\n")); err != nil {
@@ -376,12 +774,29 @@ func runHydrate(args []string, stdout, stderr io.Writer) int {
 		}
 	}
 
+	if len(plan.Conflicts()) > 0 {
+		return ExitConflict
+	}
 	if result.Valid {
 		return ExitSuccess
 	}
 	return ExitValidation
 }
 
+// undoHydrate reverts the hydration recorded in a journal file.
+func undoHydrate(path string, stderr io.Writer) int {
+	journal, err := hydrate.LoadJournal(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load journal: %v\n", err)
+		return ExitConfigError
+	}
+	if err := hydrate.Undo(journal); err != nil {
+		fmt.Fprintf(stderr, "failed to undo hydration: %v\n", err)
+		return ExitConfigError
+	}
+	return ExitSuccess
+}
+
 func decodeRoot(raw []byte) (any, error) {
 	var root any
 	if err := json.Unmarshal(raw, &root); err != nil {
@@ -421,6 +836,58 @@ func loadSchema(path string) (map[string]any, error) {
 	}
 }
 
+// renderSchema renders templates in schema. Variables come from the spec's
+// vars block, then the --vars file, then --var flags, later ones winning.
+func renderSchema(schema map[string]any, assignments []string, varsFile string) (map[string]any, error) {
+	vars := render.SchemaVars(schema)
+	if varsFile != "" {
+		fileVars, err := render.LoadFile(varsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load vars: %w", err)
+		}
+		vars = render.Merge(vars, fileVars)
+	}
+	flagVars, err := render.ParseAssignments(assignments)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := render.Schema(schema, render.Merge(vars, flagVars))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render templates: %w", err)
+	}
+	return rendered, nil
+}
+
+// resolveSources resolves contentFrom/copyFrom paths against the spec's
+// directory, or the working directory when the spec is read from stdin.
+func resolveSources(schema map[string]any, specPath string) (map[string]any, error) {
+	baseDir := filepath.Dir(specPath)
+	if specPath == "-" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		baseDir = wd
+	}
+	resolved, err := source.Resolve(schema, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sources: %w", err)
+	}
+	return resolved, nil
+}
+
+// stringList collects the values of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func writeJSON(w io.Writer, value any) error {
 	encoded, err := json.Marshal(value)
 	if err != nil {
@@ -437,10 +904,33 @@ func printUsage(w io.Writer) {
 	fmt.Fprint(w, `usage: dirschema [options] <spec>
 
 commands:
-  expand <spec>
-  export [--root DIR] [--follow-symlinks]
-  validate [--root DIR] [--format text|json] [--print-instance] <spec>
-  hydrate [--root DIR] [--format text|json] [--dry-run] <spec>
+  expand [--format json|yaml] [--indent N] [--check FILE] <spec>
+  export [--root DIR] [--follow-symlinks] [--with sha256,size,mode,content]
+         [--infer-globs [--glob-min N] [--dir-min N]]
+         [--format json|yaml|jsonnet] [--shape list|dict|schema]
+  compact [--format yaml|json|jsonnet] <schema>
+  fmt [--style auto|list|dict] [--check] <spec>...
+  lint [--format text|json] [--enable RULE,...] [--disable RULE,...] <spec>
+  lint --list-rules
+  diff [--format text|json] <old-spec> <new-spec>
+  treediff --spec SPEC [--format text|json] [--all]
+           [--var NAME=VALUE]... [--vars FILE] <dir-a> <dir-b>
+  coverage [--root DIR] [--format text|json] [--var NAME=VALUE]... [--vars FILE] <spec>
+  validate [--root DIR] [--format text|json] [--print-instance] [--warnings-as-errors]
+           [--baseline FILE | --write-baseline FILE] [--max-errors N] [--fail-fast]
+           [--var NAME=VALUE]... [--vars FILE] <spec>
+  hydrate [--root DIR] [--format text|json] [--dry-run]
+          [--update [--backup-dir DIR]] [--journal FILE]
+          [--out DIR | --out-archive FILE] [--interactive] [--record FILE]
+          [--var NAME=VALUE]... [--vars FILE] <spec>
+  hydrate --replay PLAN [--root DIR] [--format text|json] [--dry-run]
+          [--out DIR | --out-archive FILE] [--interactive] [--record FILE]
+          [--var NAME=VALUE]... [--vars FILE] [<spec>]
+  hydrate --undo JOURNAL
+  prune [--root DIR] [--format text|json] [--dry-run | --apply [--journal FILE]]
+        [--trash DIR] [--recursive] [--var NAME=VALUE]... [--vars FILE] <spec>
+  refactor --mapping FILE [--root DIR] [--format text|json] [--dry-run] [--git]
+           [--journal FILE] [--var NAME=VALUE]... [--vars FILE] <spec>
   version
 
 options must come before <spec>
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -90,6 +91,87 @@ func TestExpandCommandMixedSpec(t *testing.T) {
 }
 
 // Test 12: End-to-end validate with symlinked subdir
+func TestExpandFormatsAndCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "spec.yaml", "README.md: true\nb.txt: true\n")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := Run([]string{"expand", "--indent", "2", path}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	want := `{
+  "properties": {
+    "README.md": {
+      "oneOf": [
+        {
+          "const": true
+        },
+        {
+          "type": "object"
+        }
+      ]
+    },
+    "b.txt": {
+      "oneOf": [
+        {
+          "const": true
+        },
+        {
+          "type": "object"
+        }
+      ]
+    }
+  },
+  "required": [
+    "README.md",
+    "b.txt"
+  ],
+  "type": "object"
+}
+`
+	if stdout.String() != want {
+		t.Fatalf("unexpected output:\n%s", stdout.String())
+	}
+	committed := writeFile(t, dir, "schema.json", stdout.String())
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"expand", "--format", "yaml", path}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("yaml exit code: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "properties:\n  README.md:\n") {
+		t.Fatalf("unexpected yaml output:\n%s", stdout.String())
+	}
+	committedYAML := writeFile(t, dir, "schema.yaml", stdout.String())
+
+	// --check picks the format from the file's extension.
+	for _, file := range []string{committed, committedYAML} {
+		stdout.Reset()
+		stderr.Reset()
+		args := []string{"expand", "--check", file, path}
+		if file == committed {
+			args = []string{"expand", "--indent", "2", "--check", file, path}
+		}
+		if code := Run(args, &stdout, &stderr); code != ExitSuccess {
+			t.Fatalf("check %s: got %d want %d (stderr=%q)", file, code, ExitSuccess, stderr.String())
+		}
+		if stdout.Len() != 0 {
+			t.Fatalf("expected no output from --check, got %q", stdout.String())
+		}
+	}
+
+	writeFile(t, dir, "spec.yaml", "README.md: true\n")
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"expand", "--indent", "2", "--check", committed, path}, &stdout, &stderr); code != ExitValidation {
+		t.Fatalf("stale check: got %d want %d", code, ExitValidation)
+	}
+	if !strings.Contains(stderr.String(), "is stale") || !strings.Contains(stderr.String(), `-    "b.txt"`) {
+		t.Fatalf("expected stale diff, got %q", stderr.String())
+	}
+}
+
 func TestValidateWithSymlinkedSubdir(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("symlink behavior varies on windows")
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"dirschema/internal/expand"
+	"dirschema/internal/spec"
+)
+
+func runCompact(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	formatFlag := fs.String("format", spec.FormatYAML, "output format (yaml|json|jsonnet)")
+	if err := fs.Parse(args); err != nil {
+		return ExitConfigError
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "compact requires a single schema path")
+		return ExitConfigError
+	}
+	switch *formatFlag {
+	case spec.FormatJSON, spec.FormatYAML, spec.FormatJsonnet:
+	default:
+		fmt.Fprintln(stderr, "invalid --format (must be yaml, json or jsonnet)")
+		return ExitConfigError
+	}
+
+	// A DSL spec is expanded first, so compact also normalizes DSL.
+	schema, err := loadSchema(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+
+	dsl, issues := expand.Compact(schema)
+	for _, issue := range issues {
+		fmt.Fprintf(stderr, "warning: %s: %s; kept as rawSchema\n", issue.Path, issue.Reason)
+	}
+
+	encoded, err := spec.Marshal(dsl, *formatFlag, 2)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to encode DSL: %v\n", err)
+		return ExitConfigError
+	}
+	if _, err := stdout.Write(encoded); err != nil {
+		fmt.Fprintf(stderr, "failed to write DSL: %v\n", err)
+		return ExitConfigError
+	}
+	return ExitSuccess
+}
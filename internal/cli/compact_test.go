@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompactRoundTripsThroughExpand(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := writeJSONFile(t, dir, "schema.json", `{
+  "type": "object",
+  "properties": {
+    "README.md": {"oneOf": [{"const": true}, {"type": "object"}]},
+    "config.json": {"type": "object", "minProperties": 1},
+    "src/": {
+      "type": "object",
+      "patternProperties": {"^.*\\.go$": {"oneOf": [{"const": true}, {"type": "object"}]}},
+      "required": [],
+      "allOf": [{"not": {"propertyNames": {"not": {"pattern": "^.*\\.go$"}}}}]
+    }
+  },
+  "required": ["src/", "config.json", "README.md"]
+}`)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"compact", schemaPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("compact: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), `warning: config.json: unsupported file keyword "minProperties"; kept as rawSchema`) {
+		t.Fatalf("expected a rawSchema warning, got %q", stderr.String())
+	}
+	for _, want := range []string{"README.md: true", "rawSchema:", "'*.go': true"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("expected %q in compacted DSL:\n%s", want, stdout.String())
+		}
+	}
+	specPath := filepath.Join(dir, "spec.yaml")
+	if err := os.WriteFile(specPath, stdout.Bytes(), 0o644); err != nil {
+		t.Fatalf("write spec: %v", err)
+	}
+
+	expanded := func(path string) string {
+		t.Helper()
+		var out, errOut bytes.Buffer
+		if code := Run([]string{"expand", path}, &out, &errOut); code != ExitSuccess {
+			t.Fatalf("expand %s: got %d (stderr=%q)", path, code, errOut.String())
+		}
+		return out.String()
+	}
+	if got, want := expanded(specPath), expanded(schemaPath); got != want {
+		t.Fatalf("round trip mismatch:\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestCompactRejectsUnknownFormat(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"compact", "--format", "toml", "schema.json"}, &stdout, &stderr); code != ExitConfigError {
+		t.Fatalf("got %d want %d", code, ExitConfigError)
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"dirschema/internal/coverage"
+	"dirschema/internal/fswalk"
+)
+
+func runCoverage(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "root directory")
+	formatFlag := fs.String("format", "text", "output format (text|json)")
+	var varFlags stringList
+	fs.Var(&varFlags, "var", "template variable name=value (repeatable)")
+	varsFile := fs.String("vars", "", "template variables file (yaml|json|jsonnet)")
+	if err := fs.Parse(args); err != nil {
+		return ExitConfigError
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "coverage requires a single spec path")
+		return ExitConfigError
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		fmt.Fprintln(stderr, "invalid --format (must be text or json)")
+		return ExitConfigError
+	}
+
+	schema, err := loadSchema(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+	schema, err = renderSchema(schema, varFlags, *varsFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+
+	root := *rootFlag
+	if root == "" {
+		root, err = os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to get working directory: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to resolve root: %v\n", err)
+		return ExitConfigError
+	}
+
+	inst, err := fswalk.WalkWithSchema(root, fswalk.Options{SymlinkPolicy: fswalk.SymlinkRecord}, schema)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to walk filesystem: %v\n", err)
+		return ExitConfigError
+	}
+	report := coverage.Compute(schema, inst)
+
+	if *formatFlag == "json" {
+		if err := writeJSON(stdout, report); err != nil {
+			fmt.Fprintf(stderr, "failed to write coverage: %v\n", err)
+			return ExitConfigError
+		}
+		return ExitSuccess
+	}
+
+	fmt.Fprintf(stdout, "%-30s %7s %5s %13s %9s\n", "directory", "literal", "glob", "unconstrained", "coverage")
+	row := func(path string, dir coverage.Directory) {
+		fmt.Fprintf(stdout, "%-30s %7d %5d %13d %8.1f%%\n", path, dir.Literal, dir.Glob, dir.Unconstrained, dir.Coverage)
+	}
+	for _, dir := range report.Directories {
+		row(dir.Path, dir)
+	}
+	row("total", report.Summary)
+	if len(report.Unconstrained) > 0 {
+		fmt.Fprintln(stdout, "\nunconstrained:")
+		for _, path := range report.Unconstrained {
+			fmt.Fprintf(stdout, "  %s\n", path)
+		}
+	}
+	return ExitSuccess
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCoverageReportsUnconstrainedEntries(t *testing.T) {
+	dir := t.TempDir()
+	specPath := writeFile(t, dir, "spec.yaml", "src/:\n  \"*.go\": true\nREADME.md: true\n")
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, root, "README.md", "hi")
+	writeFile(t, root, "notes.txt", "todo")
+	writeFile(t, filepath.Join(root, "src"), "main.go", "package main")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"coverage", "--root", root, specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("coverage: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	out := stdout.String()
+	for _, want := range []string{"directory", "src/", "100.0%", "66.7%", "total", "75.0%", "unconstrained:\n  notes.txt\n"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"coverage", "--root", root, "--format", "json", specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("coverage json: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	payload := decodeJSON(t, stdout.Bytes())
+	summary, _ := payload["summary"].(map[string]any)
+	if summary["total"] != float64(4) || summary["unconstrained"] != float64(1) {
+		t.Fatalf("unexpected summary: %v", payload["summary"])
+	}
+}
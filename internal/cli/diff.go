@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"dirschema/internal/specdiff"
+)
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	formatFlag := fs.String("format", "text", "output format (text|json)")
+	if err := fs.Parse(args); err != nil {
+		return ExitConfigError
+	}
+
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "diff requires an old and a new spec path")
+		return ExitConfigError
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		fmt.Fprintln(stderr, "invalid --format (must be text or json)")
+		return ExitConfigError
+	}
+
+	before, err := loadSchema(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", fs.Arg(0), err)
+		return ExitConfigError
+	}
+	after, err := loadSchema(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", fs.Arg(1), err)
+		return ExitConfigError
+	}
+
+	changes := specdiff.Diff(before, after)
+	breaking := 0
+	for _, change := range changes {
+		if change.Breaking {
+			breaking++
+		}
+	}
+
+	if *formatFlag == "json" {
+		if changes == nil {
+			changes = []specdiff.Change{}
+		}
+		payload := map[string]any{"changes": changes, "count": len(changes), "breakingCount": breaking}
+		if err := writeJSON(stdout, payload); err != nil {
+			fmt.Fprintf(stderr, "failed to write diff: %v\n", err)
+			return ExitConfigError
+		}
+	} else {
+		for _, change := range changes {
+			label := "non-breaking"
+			if change.Breaking {
+				label = "breaking"
+			}
+			fmt.Fprintf(stdout, "%-12s %s: %s\n", label, change.Path, change.Detail)
+		}
+		fmt.Fprintf(stdout, "%d changes, %d breaking\n", len(changes), breaking)
+	}
+
+	// A breaking change may invalidate trees that pass today.
+	if breaking > 0 {
+		return ExitValidation
+	}
+	return ExitSuccess
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffReportsBreakingChanges(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := writeFile(t, dir, "old.yaml", "README.md: true\nsrc/:\n  - \"*.go\"\n")
+	relaxed := writeFile(t, dir, "relaxed.yaml", "src/:\n  - \"*.go\"\n")
+	tightened := writeFile(t, dir, "tightened.yaml", "README.md: true\nLICENSE: true\nsrc/:\n  - \"*.go\"\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"diff", oldPath, relaxed}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("diff relaxed: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	want := "non-breaking README.md: entry removed\n1 changes, 0 breaking\n"
+	if stdout.String() != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"diff", "--format", "json", oldPath, tightened}, &stdout, &stderr); code != ExitValidation {
+		t.Fatalf("diff tightened: got %d want %d (stderr=%q)", code, ExitValidation, stderr.String())
+	}
+	payload := decodeJSON(t, stdout.Bytes())
+	if payload["breakingCount"] != float64(1) {
+		t.Fatalf("expected one breaking change, got %v", payload)
+	}
+	if !strings.Contains(stdout.String(), `"path":"LICENSE","kind":"entry-added","breaking":true`) {
+		t.Fatalf("unexpected changes: %s", stdout.String())
+	}
+}
@@ -106,3 +106,155 @@ func TestExportCommandSymlink(t *testing.T) {
 		t.Fatalf("expected symlink target, got %#v", link["symlink"])
 	}
 }
+
+func TestExportWithAttributesLocksTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes vary on windows")
+	}
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "bin"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "bin", "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"export", "--root", root, "--with", "sha256,size,mode"}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+
+	var got []any
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []any{
+		map[string]any{
+			"bin/": []any{
+				map[string]any{"run.sh": map[string]any{
+					"mode":   "0755",
+					"sha256": "a8076d3d28d21e02012b20eaf7dbf75409a6277134439025f282e368e3305abf",
+					"size":   float64(10),
+				}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("export mismatch: got %#v want %#v", got, want)
+	}
+
+	lockPath := filepath.Join(dir, "lock.json")
+	if err := os.WriteFile(lockPath, stdout.Bytes(), 0o644); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"validate", "--root", root, lockPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("validate lock: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+
+	if err := os.Chmod(filepath.Join(root, "bin", "run.sh"), 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"validate", "--root", root, lockPath}, &stdout, &stderr); code != ExitValidation {
+		t.Fatalf("validate changed tree: got %d want %d", code, ExitValidation)
+	}
+}
+
+func TestExportRejectsUnknownAttribute(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := Run([]string{"export", "--root", t.TempDir(), "--with", "owner"}, &stdout, &stderr); code != ExitConfigError {
+		t.Fatalf("exit code: got %d want %d", code, ExitConfigError)
+	}
+}
+
+func TestExportInferGlobsValidates(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	for _, rel := range []string{
+		"cmd/a/main.go", "cmd/b/main.go", "cmd/c/main.go",
+		"pkg/x.go", "pkg/y.go", "pkg/z.go", "pkg/doc.txt",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("package x\n"), 0o644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"export", "--root", root, "--infer-globs"}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	var got []any
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []any{
+		map[string]any{"cmd/": []any{map[string]any{"*/": []any{"main.go"}}}},
+		map[string]any{"pkg/": []any{"*.go", "doc.txt"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("export mismatch: got %#v want %#v", got, want)
+	}
+
+	specPath := filepath.Join(dir, "spec.json")
+	if err := os.WriteFile(specPath, stdout.Bytes(), 0o644); err != nil {
+		t.Fatalf("write spec: %v", err)
+	}
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"validate", "--root", root, specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("validate: got %d want %d (stdout=%q stderr=%q)", code, ExitSuccess, stdout.String(), stderr.String())
+	}
+}
+
+func TestExportFormatsAndShapesValidate(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("yo"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	tests := []struct {
+		format, shape, ext string
+	}{
+		{"yaml", "list", "yaml"},
+		{"yaml", "dict", "yaml"},
+		{"jsonnet", "dict", "jsonnet"},
+		{"json", "schema", "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+"-"+tt.shape, func(t *testing.T) {
+			var stdout bytes.Buffer
+			var stderr bytes.Buffer
+			args := []string{"export", "--root", root, "--with", "size", "--format", tt.format, "--shape", tt.shape}
+			if code := Run(args, &stdout, &stderr); code != ExitSuccess {
+				t.Fatalf("export: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+			}
+			specPath := filepath.Join(t.TempDir(), "spec."+tt.ext)
+			if err := os.WriteFile(specPath, stdout.Bytes(), 0o644); err != nil {
+				t.Fatalf("write spec: %v", err)
+			}
+			output := stdout.String()
+			stdout.Reset()
+			stderr.Reset()
+			if code := Run([]string{"validate", "--root", root, specPath}, &stdout, &stderr); code != ExitSuccess {
+				t.Fatalf("validate: got %d want %d (stderr=%q)\n%s", code, ExitSuccess, stderr.String(), output)
+			}
+		})
+	}
+}
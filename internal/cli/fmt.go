@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dirschema/internal/dslfmt"
+	"dirschema/internal/spec"
+	"dirschema/internal/textdiff"
+)
+
+func runFmt(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	styleFlag := fs.String("style", dslfmt.StyleAuto, "directory style (auto|list|dict); auto follows the spec's root")
+	check := fs.Bool("check", false, "exit 1 if a spec is not formatted instead of rewriting it")
+	if err := fs.Parse(args); err != nil {
+		return ExitConfigError
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "fmt requires at least one spec path")
+		return ExitConfigError
+	}
+	switch *styleFlag {
+	case dslfmt.StyleAuto, dslfmt.StyleList, dslfmt.StyleDict:
+	default:
+		fmt.Fprintln(stderr, "invalid --style (must be auto, list or dict)")
+		return ExitConfigError
+	}
+
+	code := ExitSuccess
+	for _, path := range fs.Args() {
+		result := formatSpec(path, *styleFlag, *check, stdout, stderr)
+		if result > code {
+			code = result
+		}
+	}
+	return code
+}
+
+// formatSpec formats one spec in place, or writes it to stdout for "-".
+// With check it only reports whether the spec is formatted.
+func formatSpec(path, style string, check bool, stdout, stderr io.Writer) int {
+	var src []byte
+	var err error
+	if path == "-" {
+		src, err = io.ReadAll(stdin)
+	} else {
+		src, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read %s: %v\n", path, err)
+		return ExitConfigError
+	}
+
+	format, err := fmtFormat(path, src)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", path, err)
+		return ExitConfigError
+	}
+	formatted, err := dslfmt.Format(src, dslfmt.Options{Style: style, Format: format})
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to format %s: %v\n", path, err)
+		return ExitConfigError
+	}
+
+	if check {
+		if bytes.Equal(src, formatted) {
+			return ExitSuccess
+		}
+		diff, _ := textdiff.Unified(path, "formatted", string(src), string(formatted), 200)
+		fmt.Fprintf(stderr, "%s is not formatted; run dirschema fmt\n%s\n", path, diff)
+		return ExitValidation
+	}
+	if path == "-" {
+		if _, err := stdout.Write(formatted); err != nil {
+			fmt.Fprintf(stderr, "failed to write output: %v\n", err)
+			return ExitConfigError
+		}
+		return ExitSuccess
+	}
+	if bytes.Equal(src, formatted) {
+		return ExitSuccess
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to stat %s: %v\n", path, err)
+		return ExitConfigError
+	}
+	if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+		fmt.Fprintf(stderr, "failed to write %s: %v\n", path, err)
+		return ExitConfigError
+	}
+	return ExitSuccess
+}
+
+// fmtFormat picks the encoding of a spec from its extension, or from its
+// first character on stdin, as spec loading does.
+func fmtFormat(path string, src []byte) (string, error) {
+	if path == "-" {
+		if trimmed := bytes.TrimSpace(src); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			return spec.FormatJSON, nil
+		}
+		return spec.FormatYAML, nil
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return spec.FormatYAML, nil
+	case ".json":
+		return spec.FormatJSON, nil
+	case ".jsonnet":
+		return "", fmt.Errorf("jsonnet specs are not supported; use jsonnetfmt")
+	default:
+		return "", fmt.Errorf("unsupported spec extension: %s", ext)
+	}
+}
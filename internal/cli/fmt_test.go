@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFmtCheckAndRewrite(t *testing.T) {
+	dir := t.TempDir()
+	specPath := writeFile(t, dir, "spec.yaml", "src/:\n  - main.go\n  - \"a.go\"\nREADME.md: true # docs\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"fmt", "--check", specPath}, &stdout, &stderr); code != ExitValidation {
+		t.Fatalf("fmt --check: got %d want %d (stderr=%q)", code, ExitValidation, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "is not formatted") || !strings.Contains(stderr.String(), "+  a.go: true") {
+		t.Fatalf("expected a diff, got %q", stderr.String())
+	}
+
+	stderr.Reset()
+	if code := Run([]string{"fmt", specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("fmt: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	got, err := os.ReadFile(specPath)
+	if err != nil {
+		t.Fatalf("read spec: %v", err)
+	}
+	want := "README.md: true # docs\nsrc/:\n  a.go: true\n  main.go: true\n"
+	if string(got) != want {
+		t.Fatalf("formatted spec mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	if code := Run([]string{"fmt", "--check", specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("fmt --check after fmt: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+}
+
+func TestFmtRefusesInvalidSpec(t *testing.T) {
+	dir := t.TempDir()
+	original := "b.txt: false\na.txt: true\n"
+	specPath := writeFile(t, dir, "spec.yaml", original)
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"fmt", specPath}, &stdout, &stderr); code != ExitConfigError {
+		t.Fatalf("fmt: got %d want %d", code, ExitConfigError)
+	}
+	got, _ := os.ReadFile(specPath)
+	if string(got) != original {
+		t.Fatalf("spec was modified: %q", got)
+	}
+}
@@ -1,10 +1,15 @@
 package cli
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -85,3 +90,331 @@ func TestHydrateReportsInvalid(t *testing.T) {
 		t.Fatalf("expected valid=true after hydrate, got %v", payload["valid"])
 	}
 }
+
+func TestHydrateTemplates(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	spec := "vars:\n  name: default\n  owner: team\ncmd/:\n  \"{{.name}}/\":\n    main.go: true\ngo.mod:\n  template: |\n    module example.com/{{.name}}\n    // owner: {{.owner}}\n"
+	specPath := writeJSONFile(t, dir, "spec.yaml", spec)
+	varsPath := writeJSONFile(t, dir, "vars.yaml", "owner: platform\n")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	exitCode := Run([]string{"hydrate", "--root", root, "--vars", varsPath, "--var", "name=billing", specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "cmd", "billing", "main.go")); err != nil {
+		t.Fatalf("expected templated path: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatalf("read go.mod: %v", err)
+	}
+	want := "module example.com/billing\n// owner: platform\n"
+	if string(content) != want {
+		t.Fatalf("go.mod content: got %q want %q", string(content), want)
+	}
+
+	// Validation renders with the same variables; a different name fails.
+	stderr.Reset()
+	exitCode = Run([]string{"validate", "--root", root, "--vars", varsPath, "--var", "name=billing", specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("validate exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	exitCode = Run([]string{"validate", "--root", root, "--var", "name=billing", specPath}, &stdout, &stderr)
+	if exitCode != ExitValidation {
+		t.Fatalf("validate with default owner: got %d want %d", exitCode, ExitValidation)
+	}
+}
+
+func TestHydrateConflictExitCode(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "dir"), []byte("file"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	spec := `{"type":"object","properties":{"dir/":{"type":"object","properties":{"file.txt":{"const":true}},"required":["file.txt"]},"root.txt":{"const":true}},"required":["dir/","root.txt"]}`
+	specPath := writeJSONFile(t, dir, "spec.json", spec)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"hydrate", "--root", root, "--dry-run", "--format", "json", specPath}, &stdout, &stderr)
+	if exitCode != ExitConflict {
+		t.Fatalf("dry-run exit code: got %d want %d (stderr=%q)", exitCode, ExitConflict, stderr.String())
+	}
+	var payload struct {
+		Ops []struct {
+			Kind   string
+			Reason string
+		} `json:"ops"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &payload); err != nil {
+		t.Fatalf("decode plan: %v", err)
+	}
+	if len(payload.Ops) != 2 || payload.Ops[0].Kind != "conflict" || payload.Ops[0].Reason != "type-mismatch" {
+		t.Fatalf("unexpected plan: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"hydrate", "--root", root, specPath}, &stdout, &stderr)
+	if exitCode != ExitConflict {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitConflict, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "root.txt")); err != nil {
+		t.Fatalf("expected root.txt created despite conflict: %v", err)
+	}
+}
+
+func TestHydrateUpdate(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "config.ini"), []byte("old\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	specPath := writeJSONFile(t, dir, "spec.yaml", "config.ini:\n  content: \"new\\n\"\n")
+	backupDir := filepath.Join(dir, "backup")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"hydrate", "--root", root, "--backup-dir", backupDir, specPath}, &stdout, &stderr)
+	if exitCode != ExitConfigError {
+		t.Fatalf("--backup-dir without --update: got %d want %d", exitCode, ExitConfigError)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"hydrate", "--root", root, "--update", "--dry-run", specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("dry-run exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "update config.ini (content)\n  --- a/config.ini") || !strings.Contains(stdout.String(), "  -old\n  +new") {
+		t.Fatalf("expected diff in plan, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"hydrate", "--root", root, "--update", "--backup-dir", backupDir, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "config.ini")); string(data) != "new\n" {
+		t.Fatalf("unexpected content: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(backupDir, "config.ini")); string(data) != "old\n" {
+		t.Fatalf("unexpected backup: %q", data)
+	}
+}
+
+func TestHydrateJournalUndo(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	spec := `{"type":"object","properties":{"dir/":{"type":"object","properties":{"file.txt":{"const":true}},"required":["file.txt"]},"root.txt":{"const":true}},"required":["dir/","root.txt"]}`
+	specPath := writeJSONFile(t, dir, "spec.json", spec)
+	journalPath := filepath.Join(dir, "journal.json")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"hydrate", "--root", root, "--journal", journalPath, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"hydrate", "--undo", journalPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("undo exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatalf("readdir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected empty root after undo, got %d entries", len(entries))
+	}
+}
+
+func TestHydrateOutArchive(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	spec := `{"type":"object","properties":{"dir/":{"type":"object","properties":{"file.txt":{"const":true}},"required":["file.txt"]},"link":{"type":"object","properties":{"symlink":{"const":"dir/file.txt"}}}},"required":["dir/","link"]}`
+	specPath := writeJSONFile(t, dir, "spec.json", spec)
+	archive := filepath.Join(dir, "skel.tar.gz")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"hydrate", "--out-archive", archive, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var got []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar: %v", err)
+		}
+		entry := hdr.Name
+		if hdr.Typeflag == tar.TypeSymlink {
+			entry += " -> " + hdr.Linkname
+		}
+		got = append(got, entry)
+	}
+	want := []string{"dir/", "dir/file.txt", "link -> dir/file.txt"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected archive entries: %q", got)
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected root untouched, got %d entries (%v)", len(entries), err)
+	}
+}
+
+func TestHydrateOutDirFromRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	out := filepath.Join(dir, "out")
+
+	spec := `{"type":"object","properties":{"dir/":{"type":"object","properties":{"file.txt":{"const":true}},"required":["file.txt"]},"root.txt":{"const":true}},"required":["dir/","root.txt"]}`
+	specPath := writeJSONFile(t, dir, "spec.json", spec)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"hydrate", "--root", root, "--out", out, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+
+	// Only the entries missing from the root are emitted.
+	for _, rel := range []string{"root.txt", "dir/file.txt"} {
+		if _, err := os.Stat(filepath.Join(out, rel)); err != nil {
+			t.Fatalf("expected %s in output: %v", rel, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, rel)); err == nil {
+			t.Fatalf("expected %s not created in root", rel)
+		}
+	}
+}
+
+func TestHydrateInteractiveRecordReplay(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor script needs sh")
+	}
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	spec := `{"type":"object","properties":{"a.txt":{"type":"object","properties":{"content":{"const":"draft\n"}}},"b.txt":{"const":true},"c.txt":{"const":true}},"required":["a.txt","b.txt","c.txt"]}`
+	specPath := writeJSONFile(t, dir, "spec.json", spec)
+	editor := filepath.Join(dir, "editor.sh")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\nprintf 'edited\\n' > \"$1\"\n"), 0o755); err != nil {
+		t.Fatalf("write editor: %v", err)
+	}
+	t.Setenv("EDITOR", editor)
+	recordPath := filepath.Join(dir, "session.json")
+
+	// Edit and accept a.txt, skip b.txt, then run out of answers for c.txt.
+	stdin = strings.NewReader("e\ny\nn\n")
+	t.Cleanup(func() { stdin = os.Stdin })
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"hydrate", "--root", root, "--interactive", "--record", recordPath, "--dry-run", specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	if got := stdout.String(); got != "writefile a.txt (content)\n" {
+		t.Fatalf("unexpected plan output: %q", got)
+	}
+	for _, want := range []string{"  | draft", "  | edited", "(2/3) apply?", "no more input"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Fatalf("expected %q in prompts:\n%s", want, stderr.String())
+		}
+	}
+
+	replayRoot := filepath.Join(dir, "replay")
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"hydrate", "--replay", recordPath, "--root", replayRoot}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("replay exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	data, err := os.ReadFile(filepath.Join(replayRoot, "a.txt"))
+	if err != nil || string(data) != "edited\n" {
+		t.Fatalf("unexpected a.txt: %q, %v", data, err)
+	}
+	for _, name := range []string{"b.txt", "c.txt"} {
+		if _, err := os.Stat(filepath.Join(replayRoot, name)); err == nil {
+			t.Fatalf("expected %s not replayed", name)
+		}
+	}
+}
+
+func TestHydrateReplayRefusesEscapes(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	victim := filepath.Join(dir, "victim")
+	for _, d := range []string{root, victim} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	for _, plan := range []string{
+		`{"ops":[{"Kind":"writefile","RelPath":"../escaped.txt"}]}`,
+		`{"ops":[{"Kind":"remove","RelPath":"../victim","Recursive":true}]}`,
+	} {
+		planPath := writeFile(t, dir, "plan.json", plan)
+		var stdout, stderr bytes.Buffer
+		if code := Run([]string{"hydrate", "--replay", planPath, "--root", root}, &stdout, &stderr); code != ExitConfigError {
+			t.Fatalf("%s: got %d want %d (stderr=%q)", plan, code, ExitConfigError, stderr.String())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); err == nil {
+		t.Fatalf("replay wrote outside the root")
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Fatalf("replay removed a sibling directory: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"dirschema/internal/hydrate"
+)
+
+// stdin is where interactive prompts read answers from; tests replace it.
+var stdin io.Reader = os.Stdin
+
+// previewLines caps the content shown for a planned file write.
+const previewLines = 20
+
+const reviewHelp = `y - apply this op
+n - skip this op
+e - edit the file content in $EDITOR
+a - apply this and all remaining ops
+q - skip this and all remaining ops
+`
+
+// reviewPlan shows each op of plan on w and asks whether to apply it,
+// reading one answer per line from in. It returns the plan reduced to the
+// accepted ops, with any edited content. Conflicts cannot be accepted; they
+// are shown and kept so they are still reported. Running out of input skips
+// the remaining ops.
+func reviewPlan(plan hydrate.Plan, in io.Reader, w io.Writer) (hydrate.Plan, error) {
+	answers := bufio.NewScanner(in)
+	reviewed := hydrate.Plan{Skipped: plan.Skipped}
+	all := false
+
+	for i, op := range plan.Ops {
+		if op.Kind == hydrate.OpConflict {
+			fmt.Fprintf(w, "%s (cannot be applied)\n", hydrate.FormatOpsText(hydrate.Plan{Ops: []hydrate.Op{op}}))
+			reviewed.Ops = append(reviewed.Ops, op)
+			continue
+		}
+		if all {
+			reviewed.Ops = append(reviewed.Ops, op)
+			continue
+		}
+
+		showOp(w, op)
+	prompt:
+		for {
+			fmt.Fprintf(w, "(%d/%d) apply? [y,n,e,a,q,?] ", i+1, len(plan.Ops))
+			if !answers.Scan() {
+				fmt.Fprintln(w)
+				if err := answers.Err(); err != nil {
+					return hydrate.Plan{}, err
+				}
+				fmt.Fprintln(w, "no more input; skipping remaining ops")
+				return keepConflicts(reviewed, plan.Ops[i+1:]), nil
+			}
+			switch strings.ToLower(strings.TrimSpace(answers.Text())) {
+			case "y", "yes":
+				reviewed.Ops = append(reviewed.Ops, op)
+				break prompt
+			case "n", "no":
+				break prompt
+			case "a", "all":
+				reviewed.Ops = append(reviewed.Ops, op)
+				all = true
+				break prompt
+			case "q", "quit":
+				return keepConflicts(reviewed, plan.Ops[i+1:]), nil
+			case "e", "edit":
+				if op.Kind != hydrate.OpWriteFile {
+					fmt.Fprintln(w, "only file writes can be edited")
+					continue
+				}
+				edited, err := editContent(op, w)
+				if err != nil {
+					return hydrate.Plan{}, err
+				}
+				op = edited
+				showOp(w, op)
+			default:
+				fmt.Fprint(w, reviewHelp)
+			}
+		}
+	}
+	return reviewed, nil
+}
+
+// keepConflicts appends the conflicts among the unreviewed ops to plan.
+func keepConflicts(plan hydrate.Plan, rest []hydrate.Op) hydrate.Plan {
+	for _, op := range rest {
+		if op.Kind == hydrate.OpConflict {
+			plan.Ops = append(plan.Ops, op)
+		}
+	}
+	return plan
+}
+
+// showOp prints an op like the text plan, followed by a preview of the
+// content a file write creates.
+func showOp(w io.Writer, op hydrate.Op) {
+	fmt.Fprintln(w, hydrate.FormatOpsText(hydrate.Plan{Ops: []hydrate.Op{op}}))
+	if op.Kind != hydrate.OpWriteFile {
+		return
+	}
+	if op.Content == nil || *op.Content == "" {
+		fmt.Fprintln(w, "  (empty)")
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(*op.Content, "\n"), "\n")
+	for i, line := range lines {
+		if i == previewLines {
+			fmt.Fprintf(w, "  ... %d more lines\n", len(lines)-previewLines)
+			break
+		}
+		fmt.Fprintf(w, "  | %s\n", line)
+	}
+}
+
+// editContent opens the op's content in $EDITOR (vi when unset) and returns
+// the op with the saved content.
+func editContent(op hydrate.Op, w io.Writer) (hydrate.Op, error) {
+	dir, err := os.MkdirTemp("", "dirschema-edit-")
+	if err != nil {
+		return op, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, filepath.Base(op.RelPath))
+	content := ""
+	if op.Content != nil {
+		content = *op.Content
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return op, err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	// Run through the shell so EDITOR may carry arguments ("code --wait").
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		return op, fmt.Errorf("editor %q: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return op, err
+	}
+	edited := string(data)
+	op.Content = &edited
+	return op, nil
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"dirschema/internal/lint"
+	"dirschema/internal/spec"
+)
+
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	formatFlag := fs.String("format", "text", "output format (text|json)")
+	enableFlag := fs.String("enable", "", "only run these rules (comma-separated rule ids)")
+	disableFlag := fs.String("disable", "", "skip these rules (comma-separated rule ids)")
+	listRules := fs.Bool("list-rules", false, "print the rule ids and exit")
+	if err := fs.Parse(args); err != nil {
+		return ExitConfigError
+	}
+
+	if *listRules {
+		for _, rule := range lint.Rules {
+			fmt.Fprintf(stdout, "%-18s %s\n", rule.ID, rule.Description)
+		}
+		return ExitSuccess
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "lint requires a single spec path")
+		return ExitConfigError
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		fmt.Fprintln(stderr, "invalid --format (must be text or json)")
+		return ExitConfigError
+	}
+	enabled, err := lintRules(*enableFlag, *disableFlag)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+
+	loaded, err := spec.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load spec: %v\n", err)
+		return ExitConfigError
+	}
+	root, err := decodeRoot(loaded.JSON)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to parse spec json: %v\n", err)
+		return ExitConfigError
+	}
+	kind, err := spec.InferKind(root)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to infer spec kind: %v\n", err)
+		return ExitConfigError
+	}
+	if kind != spec.KindDSL {
+		fmt.Fprintln(stderr, "lint requires a DSL spec")
+		return ExitConfigError
+	}
+
+	findings, err := lint.Lint(root, enabled)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to lint spec: %v\n", err)
+		return ExitConfigError
+	}
+
+	if *formatFlag == "json" {
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		if err := writeJSON(stdout, map[string]any{"findings": findings, "count": len(findings)}); err != nil {
+			fmt.Fprintf(stderr, "failed to write findings: %v\n", err)
+			return ExitConfigError
+		}
+	} else {
+		for _, f := range findings {
+			fmt.Fprintf(stdout, "%s: %s [%s]\n", f.Path, f.Message, f.Rule)
+		}
+	}
+	if len(findings) > 0 {
+		return ExitValidation
+	}
+	return ExitSuccess
+}
+
+// lintRules turns --enable and --disable into the set of rules to run.
+func lintRules(enable, disable string) (map[string]bool, error) {
+	enabled := map[string]bool{}
+	if enable == "" {
+		for _, rule := range lint.Rules {
+			enabled[rule.ID] = true
+		}
+	}
+	for _, list := range []struct {
+		ids   string
+		value bool
+	}{{enable, true}, {disable, false}} {
+		if list.ids == "" {
+			continue
+		}
+		for _, id := range strings.Split(list.ids, ",") {
+			id = strings.TrimSpace(id)
+			if !lint.KnownRule(id) {
+				return nil, fmt.Errorf("unknown lint rule %q (see dirschema lint --list-rules)", id)
+			}
+			enabled[id] = list.value
+		}
+	}
+	return enabled, nil
+}
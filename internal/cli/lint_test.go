@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLintRulesAndToggles(t *testing.T) {
+	dir := t.TempDir()
+	specPath := writeFile(t, dir, "spec.yaml", `src/:
+  "*.go": true
+  "*_test.go": true
+data.bin:
+  size:
+    min: 10
+    max: 1
+`)
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"lint", specPath}, &stdout, &stderr); code != ExitValidation {
+		t.Fatalf("lint: got %d want %d (stderr=%q)", code, ExitValidation, stderr.String())
+	}
+	for _, want := range []string{"[overlapping-globs]", "data.bin: size.min 10 is greater than size.max 1 [size-range]"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"lint", "--format", "json", "--disable", "overlapping-globs", specPath}, &stdout, &stderr); code != ExitValidation {
+		t.Fatalf("lint --disable: got %d want %d", code, ExitValidation)
+	}
+	payload := decodeJSON(t, stdout.Bytes())
+	if payload["count"] != float64(1) {
+		t.Fatalf("expected one finding, got %v", payload)
+	}
+
+	stdout.Reset()
+	if code := Run([]string{"lint", "--enable", "content-sha256", specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("lint --enable: got %d want %d (stdout=%q)", code, ExitSuccess, stdout.String())
+	}
+
+	stderr.Reset()
+	if code := Run([]string{"lint", "--disable", "no-such-rule", specPath}, &stdout, &stderr); code != ExitConfigError {
+		t.Fatalf("lint unknown rule: got %d want %d", code, ExitConfigError)
+	}
+	if !strings.Contains(stderr.String(), `unknown lint rule "no-such-rule"`) {
+		t.Fatalf("unexpected stderr: %q", stderr.String())
+	}
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"dirschema/internal/fswalk"
+	"dirschema/internal/hydrate"
+	"dirschema/internal/instance"
+)
+
+func runPrune(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "root directory")
+	formatFlag := fs.String("format", "text", "output format (text|json)")
+	dryRun := fs.Bool("dry-run", false, "print planned removals without applying (default)")
+	apply := fs.Bool("apply", false, "remove the planned entries")
+	trashDir := fs.String("trash", "", "move removed entries into DIR instead of deleting them")
+	recursive := fs.Bool("recursive", false, "allow removing non-empty directories")
+	journalPath := fs.String("journal", "", "record removed paths in FILE for hydrate --undo")
+	var varFlags stringList
+	fs.Var(&varFlags, "var", "template variable name=value (repeatable)")
+	varsFile := fs.String("vars", "", "template variables file (yaml|json|jsonnet)")
+	if err := fs.Parse(args); err != nil {
+		return ExitConfigError
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "prune requires a single spec path")
+		return ExitConfigError
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		fmt.Fprintln(stderr, "invalid --format (must be text or json)")
+		return ExitConfigError
+	}
+	if *dryRun && *apply {
+		fmt.Fprintln(stderr, "--dry-run and --apply are mutually exclusive")
+		return ExitConfigError
+	}
+	if *journalPath != "" && !*apply {
+		fmt.Fprintln(stderr, "--journal requires --apply")
+		return ExitConfigError
+	}
+
+	specPath := fs.Arg(0)
+	schema, err := loadSchema(specPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+	schema, err = renderSchema(schema, varFlags, *varsFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+	schema, err = resolveSources(schema, specPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+
+	root := *rootFlag
+	if root == "" {
+		root, err = os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to get working directory: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to resolve root: %v\n", err)
+		return ExitConfigError
+	}
+	trash := *trashDir
+	if trash != "" {
+		trash, err = filepath.Abs(trash)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to resolve trash: %v\n", err)
+			return ExitConfigError
+		}
+	}
+
+	inst, err := fswalk.WalkWithSchema(root, instance.ScanAttributes(schema), schema)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to walk filesystem: %v\n", err)
+		return ExitConfigError
+	}
+	plan, err := hydrate.BuildPrunePlan(schema, inst, root, hydrate.PruneOptions{Recursive: *recursive})
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to build prune plan: %v\n", err)
+		return ExitConfigError
+	}
+
+	if *formatFlag == "json" {
+		payload, err := hydrate.FormatOpsJSON(plan)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to encode plan: %v\n", err)
+			return ExitConfigError
+		}
+		if _, err := stdout.Write(append(payload, '\n')); err != nil {
+			fmt.Fprintf(stderr, "failed to write plan: %v\n", err)
+			return ExitConfigError
+		}
+	} else if text := hydrate.FormatOpsText(plan); text != "" {
+		if _, err := stdout.Write([]byte(text + "\n")); err != nil {
+			fmt.Fprintf(stderr, "failed to write plan: %v\n", err)
+			return ExitConfigError
+		}
+	}
+
+	if *apply {
+		var journal hydrate.Journal
+		if err := hydrate.Apply(plan, hydrate.ApplyOptions{TrashDir: trash, Journal: &journal}); err != nil {
+			fmt.Fprintf(stderr, "failed to apply prune plan: %v\n", err)
+			return ExitConfigError
+		}
+		if *journalPath != "" {
+			if err := hydrate.WriteJournal(*journalPath, journal); err != nil {
+				fmt.Fprintf(stderr, "failed to write journal: %v\n", err)
+				return ExitConfigError
+			}
+		}
+	}
+	if len(plan.Conflicts()) > 0 {
+		return ExitConflict
+	}
+	return ExitSuccess
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPruneDryRunAndApply(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for _, name := range []string{"keep.txt", "extra.txt"} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	spec := `{"type":"object","properties":{"keep.txt":{"const":true}},"additionalProperties":false,"required":["keep.txt"]}`
+	specPath := writeJSONFile(t, dir, "spec.json", spec)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"prune", "--root", root, "--dry-run", specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	if stdout.String() != "remove extra.txt\n" {
+		t.Fatalf("unexpected plan: %q", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "extra.txt")); err != nil {
+		t.Fatalf("dry-run removed file: %v", err)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"prune", "--root", root, "--apply", specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "extra.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected extra.txt removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "keep.txt")); err != nil {
+		t.Fatalf("expected keep.txt kept: %v", err)
+	}
+}
+
+func TestPruneJournalUndo(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, root, "keep.txt", "")
+	writeFile(t, root, "extra.txt", "data")
+	spec := `{"type":"object","properties":{"keep.txt":{"const":true}},"additionalProperties":false,"required":["keep.txt"]}`
+	specPath := writeJSONFile(t, dir, "spec.json", spec)
+	journalPath := filepath.Join(dir, "journal.json")
+
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"prune", "--root", root, "--journal", journalPath, specPath}, &stdout, &stderr); code != ExitConfigError {
+		t.Fatalf("--journal without --apply: got %d want %d", code, ExitConfigError)
+	}
+
+	args := []string{"prune", "--root", root, "--apply", "--trash", filepath.Join(dir, "trash"), "--journal", journalPath, specPath}
+	if code := Run(args, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("prune: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "extra.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected extra.txt removed, got %v", err)
+	}
+
+	if code := Run([]string{"hydrate", "--undo", journalPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("undo: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "extra.txt")); err != nil || string(data) != "data" {
+		t.Fatalf("expected extra.txt restored: %q, %v", data, err)
+	}
+}
+
+func TestPruneResolvesSources(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, root, "LICENSE", "MIT")
+	specPath := writeFile(t, dir, "spec.yaml", "LICENSE:\n  contentFrom: LICENSE.tmpl\n  verify: sha256\n")
+
+	// The source is read relative to the spec, as validate and hydrate do.
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"prune", "--root", root, specPath}, &stdout, &stderr); code != ExitConfigError {
+		t.Fatalf("missing source: got %d want %d (stderr=%q)", code, ExitConfigError, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), filepath.Join(dir, "LICENSE.tmpl")) {
+		t.Fatalf("expected the resolved source in the error, got %q", stderr.String())
+	}
+
+	writeFile(t, dir, "LICENSE.tmpl", "MIT")
+	stderr.Reset()
+	if code := Run([]string{"prune", "--root", root, specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("prune: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+}
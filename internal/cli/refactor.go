@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"dirschema/internal/hydrate"
+	"dirschema/internal/refactor"
+)
+
+func runRefactor(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("refactor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	rootFlag := fs.String("root", "", "root directory")
+	formatFlag := fs.String("format", "text", "output format (text|json)")
+	mappingPath := fs.String("mapping", "", "mapping file with one \"OLD_GLOB -> NEW_PATH\" rule per line")
+	dryRun := fs.Bool("dry-run", false, "print planned moves without applying")
+	useGit := fs.Bool("git", false, "move files with git mv")
+	journalPath := fs.String("journal", "", "record moves in FILE for hydrate --undo")
+	var varFlags stringList
+	fs.Var(&varFlags, "var", "template variable name=value (repeatable)")
+	varsFile := fs.String("vars", "", "template variables file (yaml|json|jsonnet)")
+	if err := fs.Parse(args); err != nil {
+		return ExitConfigError
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "refactor requires a single spec path")
+		return ExitConfigError
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		fmt.Fprintln(stderr, "invalid --format (must be text or json)")
+		return ExitConfigError
+	}
+	if *mappingPath == "" {
+		fmt.Fprintln(stderr, "refactor requires --mapping")
+		return ExitConfigError
+	}
+
+	rules, err := refactor.LoadMapping(*mappingPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load mapping: %v\n", err)
+		return ExitConfigError
+	}
+
+	specPath := fs.Arg(0)
+	schema, err := loadSchema(specPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+	schema, err = renderSchema(schema, varFlags, *varsFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+	schema, err = resolveSources(schema, specPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+
+	root := *rootFlag
+	if root == "" {
+		root, err = os.Getwd()
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to get working directory: %v\n", err)
+			return ExitConfigError
+		}
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to resolve root: %v\n", err)
+		return ExitConfigError
+	}
+
+	plan, err := refactor.BuildPlan(root, rules)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to build refactor plan: %v\n", err)
+		return ExitConfigError
+	}
+
+	if *formatFlag == "text" {
+		if text := hydrate.FormatOpsText(plan); text != "" {
+			if _, err := stdout.Write([]byte(text + "\n")); err != nil {
+				fmt.Fprintf(stderr, "failed to write plan: %v\n", err)
+				return ExitConfigError
+			}
+		}
+	}
+
+	if *dryRun {
+		if *formatFlag == "json" {
+			payload, err := hydrate.FormatOpsJSON(plan)
+			if err != nil {
+				fmt.Fprintf(stderr, "failed to encode plan: %v\n", err)
+				return ExitConfigError
+			}
+			if _, err := stdout.Write(append(payload, '\n')); err != nil {
+				fmt.Fprintf(stderr, "failed to write plan: %v\n", err)
+				return ExitConfigError
+			}
+		}
+		if len(plan.Conflicts()) > 0 {
+			return ExitConflict
+		}
+		return ExitSuccess
+	}
+
+	opts := hydrate.ApplyOptions{}
+	if *useGit {
+		opts.GitRoot = root
+	}
+	var journal hydrate.Journal
+	opts.Journal = &journal
+	if err := hydrate.Apply(plan, opts); err != nil {
+		fmt.Fprintf(stderr, "failed to apply refactor plan (changes rolled back): %v\n", err)
+		return ExitConfigError
+	}
+	if *journalPath != "" {
+		if err := hydrate.WriteJournal(*journalPath, journal); err != nil {
+			fmt.Fprintf(stderr, "failed to write journal: %v\n", err)
+			return ExitConfigError
+		}
+	}
+
+	return validateApplied(schema, root, plan, *formatFlag, stdout, stderr)
+}
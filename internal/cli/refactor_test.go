@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRefactorMovesAndValidates(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "lib"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for _, name := range []string{"a.py", "b.py"} {
+		if err := os.WriteFile(filepath.Join(root, "lib", name), nil, 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	specPath := writeJSONFile(t, dir, "spec.yaml", "src/:\n  pkg/:\n    a.py: true\n    b.py: true\n")
+	mappingPath := writeJSONFile(t, dir, "mapping.txt", "lib/*.py -> src/pkg/$1.py\n")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	exitCode := Run([]string{"refactor", "--root", root, "--mapping", mappingPath, "--dry-run", specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("dry-run exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	if stdout.String() != "move lib/a.py -> src/pkg/a.py\nmove lib/b.py -> src/pkg/b.py\n" {
+		t.Fatalf("unexpected plan: %q", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "lib", "a.py")); err != nil {
+		t.Fatalf("dry-run moved file: %v", err)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"refactor", "--root", root, "--mapping", mappingPath, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(root, "src", "pkg", "b.py")); err != nil {
+		t.Fatalf("expected b.py moved: %v", err)
+	}
+}
+
+func TestRefactorGitMove(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "old.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "old.txt"}} {
+		if out, err := exec.Command("git", append([]string{"-C", root}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	specPath := writeJSONFile(t, dir, "spec.yaml", "new.txt: true\n")
+	mappingPath := writeJSONFile(t, dir, "mapping.txt", "old.txt -> new.txt\n")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	journalPath := filepath.Join(dir, "journal.json")
+	exitCode := Run([]string{"refactor", "--root", root, "--mapping", mappingPath, "--git", "--journal", journalPath, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	out, err := exec.Command("git", "-C", root, "status", "--porcelain").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git status: %v: %s", err, out)
+	}
+	// With no commit yet, a git mv of a staged file shows as the new path
+	// being added; a plain rename would leave old.txt staged and new.txt
+	// untracked.
+	if string(out) != "A  new.txt\n" {
+		t.Fatalf("expected git mv in the index, got %q", out)
+	}
+
+	// Undo moves the file back with git mv, so the index follows.
+	stderr.Reset()
+	if code := Run([]string{"hydrate", "--undo", journalPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("undo: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	out, err = exec.Command("git", "-C", root, "status", "--porcelain").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git status: %v: %s", err, out)
+	}
+	if string(out) != "A  old.txt\n" {
+		t.Fatalf("expected undo to git mv back, got %q", out)
+	}
+}
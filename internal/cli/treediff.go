@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"dirschema/internal/fswalk"
+	"dirschema/internal/instance"
+	"dirschema/internal/treediff"
+)
+
+func runTreediff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("treediff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	specPath := fs.String("spec", "", "spec whose entries and attributes are compared")
+	formatFlag := fs.String("format", "text", "output format (text|json)")
+	all := fs.Bool("all", false, "also compare entries and attributes the spec does not mention")
+	var varFlags stringList
+	fs.Var(&varFlags, "var", "template variable name=value (repeatable)")
+	varsFile := fs.String("vars", "", "template variables file (yaml|json|jsonnet)")
+	if err := fs.Parse(args); err != nil {
+		return ExitConfigError
+	}
+
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "treediff requires two directories")
+		return ExitConfigError
+	}
+	if *specPath == "" {
+		fmt.Fprintln(stderr, "treediff requires --spec")
+		return ExitConfigError
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		fmt.Fprintln(stderr, "invalid --format (must be text or json)")
+		return ExitConfigError
+	}
+
+	schema, err := loadSchema(*specPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+	schema, err = renderSchema(schema, varFlags, *varsFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return ExitConfigError
+	}
+
+	// Both trees are walked the way validate walks them, so attributes and
+	// symlinks are recorded alike.
+	walkOpts := instance.ScanAttributes(schema)
+	var trees [2]map[string]any
+	for i, dir := range fs.Args() {
+		trees[i], err = fswalk.WalkWithSchema(dir, walkOpts, schema)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to walk %s: %v\n", dir, err)
+			return ExitConfigError
+		}
+	}
+
+	changes := treediff.Diff(schema, trees[0], trees[1], *all)
+	counts := map[string]int{}
+	for _, change := range changes {
+		counts[change.Kind]++
+	}
+
+	if *formatFlag == "json" {
+		if changes == nil {
+			changes = []treediff.Change{}
+		}
+		payload := map[string]any{
+			"changes": changes,
+			"added":   counts[treediff.KindAdded],
+			"removed": counts[treediff.KindRemoved],
+			"changed": counts[treediff.KindChanged],
+		}
+		if err := writeJSON(stdout, payload); err != nil {
+			fmt.Fprintf(stderr, "failed to write diff: %v\n", err)
+			return ExitConfigError
+		}
+	} else {
+		marks := map[string]string{treediff.KindAdded: "+", treediff.KindRemoved: "-", treediff.KindChanged: "~"}
+		for _, change := range changes {
+			line := marks[change.Kind] + " " + change.Path
+			if len(change.Details) > 0 {
+				line += ": " + strings.Join(change.Details, ", ")
+			}
+			fmt.Fprintln(stdout, line)
+		}
+		fmt.Fprintf(stdout, "%d added, %d removed, %d changed\n",
+			counts[treediff.KindAdded], counts[treediff.KindRemoved], counts[treediff.KindChanged])
+	}
+
+	if len(changes) > 0 {
+		return ExitValidation
+	}
+	return ExitSuccess
+}
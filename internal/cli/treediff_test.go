@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTreediffComparesThroughSpec(t *testing.T) {
+	dir := t.TempDir()
+	specPath := writeFile(t, dir, "spec.yaml", "bin/:\n  app:\n    sha256: placeholder\nREADME.md: true\n")
+	for _, tree := range []struct {
+		name, app, readme string
+	}{
+		{"a", "v1", "one"},
+		{"b", "v2", "two"},
+	} {
+		root := filepath.Join(dir, tree.name)
+		if err := os.MkdirAll(filepath.Join(root, "bin"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		writeFile(t, root, "README.md", tree.readme)
+		writeFile(t, filepath.Join(root, "bin"), "app", tree.app)
+	}
+	writeFile(t, filepath.Join(dir, "b"), "extra.txt", "ignored")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"treediff", "--spec", specPath, filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	if code := Run(args, &stdout, &stderr); code != ExitValidation {
+		t.Fatalf("treediff: got %d want %d (stderr=%q)", code, ExitValidation, stderr.String())
+	}
+	want := "~ bin/app: sha256 3bfc269594ef -> fb04dcb6970e\n0 added, 0 removed, 1 changed\n"
+	if stdout.String() != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	args = []string{"treediff", "--spec", specPath, "--format", "json", filepath.Join(dir, "a"), filepath.Join(dir, "a")}
+	if code := Run(args, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("treediff same tree: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	if payload := decodeJSON(t, stdout.Bytes()); payload["changed"] != float64(0) {
+		t.Fatalf("expected no changes, got %v", payload)
+	}
+}
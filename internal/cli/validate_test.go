@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -146,3 +148,251 @@ func TestValidateDefaultCommand(t *testing.T) {
 		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
 	}
 }
+
+func TestValidateWarningsDoNotFail(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	specPath := writeJSONFile(t, dir, "spec.yaml", "NOTES.md:\n  severity: warning\n")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	exitCode := Run([]string{"validate", "--root", root, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "warning: missing properties: 'NOTES.md'") {
+		t.Fatalf("expected warning in report, got %q", stderr.String())
+	}
+	if !strings.HasSuffix(stderr.String(), "0 errors, 1 warning\n") {
+		t.Fatalf("expected warning count summary, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"validate", "--root", root, "--warnings-as-errors", specPath}, &stdout, &stderr)
+	if exitCode != ExitValidation {
+		t.Fatalf("exit code: got %d want %d", exitCode, ExitValidation)
+	}
+}
+
+func TestValidateExpandedSpecWithAnnotations(t *testing.T) {
+	for _, tc := range []struct {
+		name, spec, want string
+	}{
+		{"vars", "vars:\n  name: app\n\"{{.name}}/\":\n  main.go: true\n", ""},
+		{"root severity", "severity: warning\nNOTES.md: true\n", "0 errors, 1 warning\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "root")
+			if err := os.MkdirAll(filepath.Join(root, "app"), 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			writeFile(t, filepath.Join(root, "app"), "main.go", "package main")
+			specPath := writeFile(t, dir, "spec.yaml", tc.spec)
+
+			var stdout, stderr bytes.Buffer
+			if code := Run([]string{"expand", specPath}, &stdout, &stderr); code != ExitSuccess {
+				t.Fatalf("expand: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+			}
+			schemaPath := writeFile(t, dir, "schema.json", stdout.String())
+
+			stdout.Reset()
+			stderr.Reset()
+			if code := Run([]string{"validate", "--root", root, schemaPath}, &stdout, &stderr); code != ExitSuccess {
+				t.Fatalf("validate: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+			}
+			if !strings.HasSuffix(stderr.String(), tc.want) {
+				t.Fatalf("unexpected report: %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestValidateBaseline(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "b.txt"), nil, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	specPath := writeJSONFile(t, dir, "spec.yaml", "a.txt: true\nsub/:\n  c.txt: true\n")
+	baselinePath := filepath.Join(dir, ".dirschema-baseline.json")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	exitCode := Run([]string{"validate", "--root", root, "--write-baseline", baselinePath, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("write-baseline exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+
+	// Same violations: all suppressed.
+	stderr.Reset()
+	exitCode = Run([]string{"validate", "--root", root, "--baseline", baselinePath, specPath}, &stdout, &stderr)
+	if exitCode != ExitSuccess {
+		t.Fatalf("baseline exit code: got %d want %d (stderr=%q)", exitCode, ExitSuccess, stderr.String())
+	}
+
+	// Fix the root violation, introduce a new one under sub/.
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), nil, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	exitCode = Run([]string{"validate", "--root", root, "--baseline", baselinePath, "--format", "json", specPath}, &stdout, &stderr)
+	if exitCode != ExitValidation {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitValidation, stderr.String())
+	}
+
+	var payload struct {
+		Errors []struct {
+			InstancePath string `json:"instancePath"`
+		} `json:"errors"`
+		Baseline struct {
+			Fixed []struct {
+				InstancePath string `json:"instancePath"`
+				Keyword      string `json:"keyword"`
+				Name         string `json:"name"`
+			} `json:"fixed"`
+		} `json:"baseline"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &payload); err != nil {
+		t.Fatalf("decode stdout: %v", err)
+	}
+	if len(payload.Errors) != 1 || payload.Errors[0].InstancePath != "/sub~1" {
+		t.Fatalf("expected only the new sub/ violation, got %+v", payload.Errors)
+	}
+	// The root was missing both a.txt and sub/; each is recorded, and fixed,
+	// on its own.
+	fixed := payload.Baseline.Fixed
+	if len(fixed) != 2 || fixed[0].Keyword != "required" || fixed[0].Name != "a.txt" || fixed[1].Name != "sub/" {
+		t.Fatalf("expected fixed root required entries, got %+v", fixed)
+	}
+}
+
+func TestValidateBaselineReportsNewMissingSibling(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, root, "b.txt", "")
+	baselinePath := filepath.Join(dir, "baseline.json")
+
+	specPath := writeJSONFile(t, dir, "spec.yaml", "a.txt: true\nb.txt: true\n")
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"validate", "--root", root, "--write-baseline", baselinePath, specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("write-baseline: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+
+	specPath = writeJSONFile(t, dir, "spec.yaml", "a.txt: true\nb.txt: true\nc.txt: true\n")
+	stderr.Reset()
+	if code := Run([]string{"validate", "--root", root, "--baseline", baselinePath, specPath}, &stdout, &stderr); code != ExitValidation {
+		t.Fatalf("baseline: got %d want %d (stderr=%q)", code, ExitValidation, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "missing properties: 'c.txt' ") || strings.Contains(stderr.String(), "a.txt") {
+		t.Fatalf("expected only c.txt reported, got %q", stderr.String())
+	}
+}
+
+func TestValidateFailFastRejectsBaselines(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	specPath := writeJSONFile(t, dir, "spec.yaml", "docs/:\n  index.md: true\nsub/:\n  a.txt: true\n")
+	baselinePath := filepath.Join(dir, "baseline.json")
+
+	var stdout, stderr bytes.Buffer
+	exitCode := Run([]string{"validate", "--root", root, "--fail-fast", "--write-baseline", baselinePath, specPath}, &stdout, &stderr)
+	if exitCode != ExitConfigError {
+		t.Fatalf("write-baseline exit code: got %d want %d", exitCode, ExitConfigError)
+	}
+	if _, err := os.Stat(baselinePath); err == nil {
+		t.Fatalf("expected no baseline written")
+	}
+
+	// The baseline records sub/ missing a.txt. A fail-fast run stops at the
+	// missing docs/ without reading sub/, so it must not call that entry
+	// fixed.
+	if code := Run([]string{"validate", "--root", root, "--write-baseline", baselinePath, specPath}, &stdout, &stderr); code != ExitSuccess {
+		t.Fatalf("write-baseline: got %d want %d (stderr=%q)", code, ExitSuccess, stderr.String())
+	}
+	stderr.Reset()
+	exitCode = Run([]string{"validate", "--root", root, "--fail-fast", "--baseline", baselinePath, specPath}, &stdout, &stderr)
+	if exitCode != ExitConfigError {
+		t.Fatalf("baseline exit code: got %d want %d (stderr=%q)", exitCode, ExitConfigError, stderr.String())
+	}
+	if strings.Contains(stderr.String(), "fixed") {
+		t.Fatalf("unexpected fixed report: %q", stderr.String())
+	}
+}
+
+func TestValidateMaxErrors(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "a"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "b"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	specPath := writeJSONFile(t, dir, "spec.yaml", "a/:\n  x.txt: true\nb/:\n  y.txt: true\n")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	exitCode := Run([]string{"validate", "--root", root, "--max-errors", "1", "--format", "json", specPath}, &stdout, &stderr)
+	if exitCode != ExitValidation {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitValidation, stderr.String())
+	}
+	payload := decodeJSON(t, stdout.Bytes())
+	if errs, _ := payload["errors"].([]any); len(errs) != 1 {
+		t.Fatalf("expected 1 reported error, got %v", payload["errors"])
+	}
+	if payload["truncated"] != float64(1) || payload["errorCount"] != float64(2) {
+		t.Fatalf("expected truncated=1 errorCount=2, got %v", payload)
+	}
+}
+
+func TestValidateFailFastSkipsWalkOnInvalidRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", "b.txt"), nil, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	specPath := writeJSONFile(t, dir, "spec.yaml", "docs/:\n  index.md: true\nsub/:\n  a.txt: true\n")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	exitCode := Run([]string{"validate", "--root", root, "--fail-fast", "--print-instance", specPath}, &stdout, &stderr)
+	if exitCode != ExitValidation {
+		t.Fatalf("exit code: got %d want %d (stderr=%q)", exitCode, ExitValidation, stderr.String())
+	}
+
+	// The root is missing docs/, so sub/ is never read.
+	instance := decodeJSON(t, stdout.Bytes())
+	if !reflect.DeepEqual(instance, map[string]any{"sub/": map[string]any{}}) {
+		t.Fatalf("expected shallow instance, got %v", instance)
+	}
+	want := "/: missing properties: 'docs/' (keyword=required, schemaPath="
+	if !strings.HasPrefix(stderr.String(), want) || strings.Count(stderr.String(), "\n") != 1 {
+		t.Fatalf("expected a single missing docs/ report, got %q", stderr.String())
+	}
+}
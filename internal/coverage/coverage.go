@@ -0,0 +1,88 @@
+// Package coverage reports how much of a walked tree a schema describes.
+package coverage
+
+import (
+	"sort"
+	"strings"
+
+	"dirschema/internal/fswalk"
+)
+
+// Directory counts how the entries directly inside a directory are matched.
+type Directory struct {
+	Path          string  `json:"path,omitempty"`
+	Literal       int     `json:"literal"`
+	Glob          int     `json:"glob"`
+	Unconstrained int     `json:"unconstrained"`
+	Total         int     `json:"total"`
+	Coverage      float64 `json:"coverage"`
+}
+
+// Report is the coverage of a tree. Summary totals all directories and has
+// no path. Unconstrained lists the entries no
+// property or pattern matches; the contents of an unconstrained directory
+// are not listed or counted.
+type Report struct {
+	Directories   []Directory `json:"directories"`
+	Unconstrained []string    `json:"unconstrained"`
+	Summary       Directory   `json:"summary"`
+}
+
+// Compute classifies every entry of inst (as produced by the walker) as
+// matched by a literal property, matched by a glob pattern, or
+// unconstrained, using the walker's schema lookups. Directories are listed
+// by path with the root as ".".
+func Compute(schema, inst map[string]any) Report {
+	r := Report{Unconstrained: []string{}}
+	r.dir("", schema, inst)
+	sort.Slice(r.Directories, func(i, j int) bool { return r.Directories[i].Path < r.Directories[j].Path })
+	sort.Strings(r.Unconstrained)
+	r.Summary.Coverage = percent(r.Summary)
+	return r
+}
+
+func (r *Report) dir(path string, schema, node map[string]any) {
+	stats := Directory{Path: path}
+	if path == "" {
+		stats.Path = "."
+	}
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childSchema, match := fswalk.MatchEntry(key, schema)
+		switch match {
+		case fswalk.MatchLiteral:
+			stats.Literal++
+		case fswalk.MatchGlob:
+			stats.Glob++
+		default:
+			stats.Unconstrained++
+			r.Unconstrained = append(r.Unconstrained, path+key)
+			continue
+		}
+		if child, ok := node[key].(map[string]any); ok && strings.HasSuffix(key, "/") {
+			r.dir(path+key, childSchema, child)
+		}
+	}
+
+	stats.Total = stats.Literal + stats.Glob + stats.Unconstrained
+	stats.Coverage = percent(stats)
+	r.Directories = append(r.Directories, stats)
+	r.Summary.Literal += stats.Literal
+	r.Summary.Glob += stats.Glob
+	r.Summary.Unconstrained += stats.Unconstrained
+	r.Summary.Total += stats.Total
+}
+
+// percent is the share of entries matched; an empty directory is fully
+// covered.
+func percent(d Directory) float64 {
+	if d.Total == 0 {
+		return 100
+	}
+	return float64(d.Literal+d.Glob) * 100 / float64(d.Total)
+}
@@ -0,0 +1,52 @@
+package coverage
+
+import (
+	"reflect"
+	"testing"
+
+	"dirschema/internal/expand"
+)
+
+func TestComputeClassifiesEntries(t *testing.T) {
+	schema, err := expand.ExpandDSL(map[string]any{
+		"README.md": true,
+		"src/": map[string]any{
+			"main.go": true,
+			"*.go":    true,
+		},
+		"docs/": map[string]any{},
+	})
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+	inst := map[string]any{
+		"README.md": true,
+		"notes.txt": true,
+		"build/": map[string]any{
+			"out.bin": true,
+		},
+		"src/": map[string]any{
+			"main.go":  true,
+			"util.go":  true,
+			"Makefile": true,
+		},
+		"docs/": map[string]any{},
+	}
+
+	r := Compute(schema, inst)
+	want := []Directory{
+		{Path: ".", Literal: 3, Unconstrained: 2, Total: 5, Coverage: 60},
+		{Path: "docs/", Total: 0, Coverage: 100},
+		{Path: "src/", Literal: 1, Glob: 1, Unconstrained: 1, Total: 3, Coverage: float64(2) * 100 / 3},
+	}
+	if !reflect.DeepEqual(r.Directories, want) {
+		t.Fatalf("directories:\n got %+v\nwant %+v", r.Directories, want)
+	}
+	// build/ is unconstrained, so its contents are neither listed nor counted.
+	if want := []string{"build/", "notes.txt", "src/Makefile"}; !reflect.DeepEqual(r.Unconstrained, want) {
+		t.Fatalf("unconstrained: got %v want %v", r.Unconstrained, want)
+	}
+	if r.Summary.Total != 8 || r.Summary.Literal != 4 || r.Summary.Glob != 1 || r.Summary.Coverage != 62.5 {
+		t.Fatalf("unexpected summary: %+v", r.Summary)
+	}
+}
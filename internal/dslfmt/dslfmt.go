@@ -0,0 +1,385 @@
+// Package dslfmt rewrites DSL specs into a canonical form.
+package dslfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"dirschema/internal/expand"
+	"dirschema/internal/spec"
+)
+
+// Directory styles accepted by Options.Style.
+const (
+	StyleAuto = "auto"
+	StyleList = "list"
+	StyleDict = "dict"
+)
+
+// Options controls Format.
+type Options struct {
+	// Style is how directories are written: StyleList ("- name" items),
+	// StyleDict ("name: value" pairs) or StyleAuto to follow the root.
+	Style string
+	// Format is the encoding of the spec, spec.FormatYAML or
+	// spec.FormatJSON. JSON specs carry no comments.
+	Format string
+}
+
+// Format rewrites a DSL spec: entries are sorted by name as expand sorts
+// them, every directory uses one style, entry names are quoted only where
+// YAML needs it, and descriptor keys are sorted. Comments are kept. The
+// result is checked to expand to the same schema as src; Format refuses to
+// return output that would change the spec's meaning.
+func Format(src []byte, opts Options) ([]byte, error) {
+	if opts.Format != spec.FormatYAML && opts.Format != spec.FormatJSON {
+		return nil, fmt.Errorf("cannot format %s specs", opts.Format)
+	}
+	before, err := expandSource(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return nil, errors.New("spec must be a single document")
+	}
+	if hasAlias(&doc) {
+		return nil, errors.New("specs with YAML anchors or aliases cannot be formatted")
+	}
+
+	style := opts.Style
+	switch style {
+	case StyleAuto, "":
+		style = StyleDict
+		if doc.Content[0].Kind == yaml.SequenceNode {
+			style = StyleList
+		}
+	case StyleList, StyleDict:
+	default:
+		return nil, fmt.Errorf("unknown style %q (want auto, list or dict)", opts.Style)
+	}
+
+	detachHeader(&doc)
+	root, err := formatDir(doc.Content[0], style, true)
+	if err != nil {
+		return nil, err
+	}
+	doc.Content[0] = root
+
+	var out []byte
+	if opts.Format == spec.FormatJSON {
+		var value any
+		if err := doc.Decode(&value); err != nil {
+			return nil, err
+		}
+		out, err = spec.Marshal(value, spec.FormatJSON, 2)
+	} else {
+		out, err = encodeYAML(&doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := expandSource(out)
+	if err != nil {
+		return nil, fmt.Errorf("formatted spec does not expand: %w", err)
+	}
+	if !bytes.Equal(before, after) {
+		return nil, errors.New("formatting would change the expanded schema; spec left unchanged")
+	}
+	return out, nil
+}
+
+// expandSource expands a DSL spec into its canonical JSON encoding, the
+// form Format compares before and after rewriting.
+func expandSource(src []byte) ([]byte, error) {
+	loaded, err := spec.LoadFromReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	var root any
+	if err := json.Unmarshal(loaded.JSON, &root); err != nil {
+		return nil, err
+	}
+	kind, err := spec.InferKind(root)
+	if err != nil {
+		return nil, err
+	}
+	if kind != spec.KindDSL {
+		return nil, errors.New("only DSL specs can be formatted")
+	}
+	schema, err := expand.ExpandDSL(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand DSL: %w", err)
+	}
+	return json.Marshal(expand.Canonicalize(schema))
+}
+
+func encodeYAML(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// detachHeader keeps a file header at the top. yaml.v3 only gives the
+// document a comment that a blank line separates from the first entry;
+// without one, the header is the first entry's head comment and would move
+// with that entry when entries are sorted. A document that already has a
+// header keeps the first entry's comment on the entry.
+func detachHeader(doc *yaml.Node) {
+	root := doc.Content[0]
+	if doc.HeadComment != "" || root.HeadComment != "" || len(root.Content) == 0 {
+		return
+	}
+	first := root.Content[0]
+	nodes := []*yaml.Node{first}
+	if root.Kind == yaml.SequenceNode && first.Kind == yaml.MappingNode && len(first.Content) > 0 {
+		nodes = append(nodes, first.Content[0])
+	}
+	for _, node := range nodes {
+		if node.HeadComment != "" {
+			doc.HeadComment = node.HeadComment
+			node.HeadComment = ""
+			return
+		}
+	}
+}
+
+// entry is one file, directory or annotation of a directory. value is nil
+// for list items that name a file.
+type entry struct {
+	key   *yaml.Node
+	value *yaml.Node
+	// comments of a list item, moved onto the entry when it is rewritten.
+	head, line, foot string
+}
+
+// formatDir rewrites a directory node in style with its entries sorted.
+func formatDir(node *yaml.Node, style string, root bool) (*yaml.Node, error) {
+	entries, err := dirEntries(node)
+	if err != nil {
+		return nil, err
+	}
+
+	var vars *entry
+	rest := make([]entry, 0, len(entries))
+	for i := range entries {
+		e := entries[i]
+		name := e.key.Value
+		switch {
+		case root && name == expand.VarsKey && e.value != nil && e.value.Kind == yaml.MappingNode:
+			sortMappings(e.value)
+			vars = &e
+			continue
+		case strings.HasSuffix(name, "/") && isDirValue(e.value):
+			e.value, err = formatDir(e.value, style, false)
+			if err != nil {
+				return nil, err
+			}
+		case e.value != nil:
+			sortMappings(e.value)
+		}
+		e.key.Style = 0
+		e.key.Tag = "!!str"
+		rest = append(rest, e)
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].key.Value < rest[j].key.Value })
+	if vars != nil {
+		rest = append([]entry{*vars}, rest...)
+	}
+
+	out := &yaml.Node{
+		HeadComment: node.HeadComment,
+		LineComment: node.LineComment,
+		FootComment: node.FootComment,
+	}
+	if style == StyleList {
+		out.Kind, out.Tag = yaml.SequenceNode, "!!seq"
+		for _, e := range rest {
+			out.Content = append(out.Content, listItem(e))
+		}
+	} else {
+		out.Kind, out.Tag = yaml.MappingNode, "!!map"
+		for _, e := range rest {
+			key, value := dictPair(e)
+			out.Content = append(out.Content, key, value)
+		}
+	}
+	if len(out.Content) == 0 {
+		out.Style = yaml.FlowStyle
+	}
+	return out, nil
+}
+
+// dirEntries lists the entries of a directory in either style. An empty
+// value ("dir/:") has none.
+func dirEntries(node *yaml.Node) ([]entry, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		entries := make([]entry, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			entries = append(entries, entry{key: node.Content[i], value: node.Content[i+1]})
+		}
+		return entries, nil
+	case yaml.SequenceNode:
+		entries := make([]entry, 0, len(node.Content))
+		for _, item := range node.Content {
+			switch {
+			case item.Kind == yaml.ScalarNode:
+				entries = append(entries, entry{key: item})
+			case item.Kind == yaml.MappingNode && len(item.Content) == 2:
+				entries = append(entries, entry{
+					key:   item.Content[0],
+					value: item.Content[1],
+					head:  item.HeadComment,
+					line:  item.LineComment,
+					foot:  item.FootComment,
+				})
+			default:
+				return nil, fmt.Errorf("line %d: list entry must be a name or a single-key map", item.Line)
+			}
+		}
+		return entries, nil
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("line %d: directory must be a map or list", node.Line)
+}
+
+// isDirValue reports whether a directory entry's value lists entries (as
+// opposed to, say, a raw schema, which is left as written).
+func isDirValue(value *yaml.Node) bool {
+	if value == nil {
+		return false
+	}
+	switch value.Kind {
+	case yaml.SequenceNode:
+		return true
+	case yaml.ScalarNode:
+		return value.Tag == "!!null"
+	case yaml.MappingNode:
+		return !(len(value.Content) == 2 && value.Content[0].Value == expand.RawSchemaKey)
+	}
+	return false
+}
+
+// isTrue reports whether value makes an entry an existence-only file.
+func isTrue(value *yaml.Node) bool {
+	return value == nil || (value.Kind == yaml.ScalarNode && value.Tag == "!!bool" && value.Value == "true")
+}
+
+// listItem writes an entry as a list item: a bare name for existence-only
+// files, a single-key map otherwise.
+func listItem(e entry) *yaml.Node {
+	if !strings.HasSuffix(e.key.Value, "/") && isTrue(e.value) {
+		item := e.key
+		item.HeadComment = joinComments(e.head, item.HeadComment)
+		item.LineComment = joinComments(e.line, item.LineComment)
+		if e.value != nil {
+			item.LineComment = joinComments(item.LineComment, e.value.LineComment)
+		}
+		item.FootComment = joinComments(item.FootComment, e.foot)
+		return item
+	}
+	// A comment above the name belongs above the "- ".
+	head := joinComments(e.head, e.key.HeadComment)
+	e.key.HeadComment = ""
+	return &yaml.Node{
+		Kind:        yaml.MappingNode,
+		Tag:         "!!map",
+		HeadComment: head,
+		LineComment: e.line,
+		FootComment: e.foot,
+		Content:     []*yaml.Node{e.key, entryValue(e)},
+	}
+}
+
+// dictPair writes an entry as a key and value, with "true" for files named
+// by a bare list item.
+func dictPair(e entry) (*yaml.Node, *yaml.Node) {
+	key := e.key
+	key.HeadComment = joinComments(e.head, key.HeadComment)
+	key.FootComment = joinComments(key.FootComment, e.foot)
+	value := entryValue(e)
+	if e.value == nil {
+		// A list item's line comment trails its name; keep it on the line.
+		value.LineComment = joinComments(key.LineComment, e.line)
+		key.LineComment = ""
+	} else {
+		value.LineComment = joinComments(e.line, value.LineComment)
+	}
+	return key, value
+}
+
+// entryValue returns the value of an entry, true for a bare file name.
+func entryValue(e entry) *yaml.Node {
+	if e.value != nil {
+		return e.value
+	}
+	if strings.HasSuffix(e.key.Value, "/") {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"}
+}
+
+// sortMappings sorts the keys of every mapping in node. Sequences keep
+// their order.
+func sortMappings(node *yaml.Node) {
+	for _, child := range node.Content {
+		sortMappings(child)
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i][0].Value < pairs[j][0].Value })
+	node.Content = node.Content[:0]
+	for _, pair := range pairs {
+		node.Content = append(node.Content, pair[0], pair[1])
+	}
+}
+
+func hasAlias(node *yaml.Node) bool {
+	if node.Kind == yaml.AliasNode || node.Anchor != "" {
+		return true
+	}
+	for _, child := range node.Content {
+		if hasAlias(child) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinComments(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + "\n" + b
+	}
+}
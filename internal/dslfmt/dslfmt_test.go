@@ -0,0 +1,148 @@
+package dslfmt
+
+import (
+	"strings"
+	"testing"
+
+	"dirschema/internal/spec"
+)
+
+const messy = `# project layout
+
+# sources
+src/:
+  - main.go # entry point
+  - "*.go"
+  - b.txt:
+      size: 3
+      content: abc
+vars:
+  name: demo
+README.md: true
+'docs/': {}
+`
+
+func TestFormatDict(t *testing.T) {
+	got, err := Format([]byte(messy), Options{Style: StyleAuto, Format: spec.FormatYAML})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	want := `# project layout
+
+vars:
+  name: demo
+README.md: true
+docs/: {}
+# sources
+src/:
+  '*.go': true
+  b.txt:
+    content: abc
+    size: 3
+  main.go: true # entry point
+`
+	if string(got) != want {
+		t.Fatalf("Format mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := Format(got, Options{Style: StyleAuto, Format: spec.FormatYAML})
+	if err != nil {
+		t.Fatalf("Format again: %v", err)
+	}
+	if string(again) != string(got) {
+		t.Fatalf("Format is not idempotent:\n%s", again)
+	}
+}
+
+func TestFormatList(t *testing.T) {
+	got, err := Format([]byte(messy), Options{Style: StyleList, Format: spec.FormatYAML})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	want := `# project layout
+
+- vars:
+    name: demo
+- README.md
+- docs/: []
+# sources
+- src/:
+    - '*.go'
+    - b.txt:
+        content: abc
+        size: 3
+    - main.go # entry point
+`
+	if string(got) != want {
+		t.Fatalf("Format mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatKeepsHeaderAtTop(t *testing.T) {
+	for _, tc := range []struct {
+		name, src, want string
+	}{
+		{
+			"dict",
+			"# generated layout\n# do not edit\nzz.txt: true\naa.txt: true\n",
+			"# generated layout\n# do not edit\n\naa.txt: true\nzz.txt: true\n",
+		},
+		{
+			"list",
+			"# generated layout\n- zz.txt\n- aa.txt\n",
+			"# generated layout\n\n- aa.txt\n- zz.txt\n",
+		},
+		{
+			"list with directory first",
+			"# generated layout\n- zz/:\n    - a.txt\n- aa.txt\n",
+			"# generated layout\n\n- aa.txt\n- zz/:\n    - a.txt\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Format([]byte(tc.src), Options{Style: StyleAuto, Format: spec.FormatYAML})
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Fatalf("Format mismatch:\ngot:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	src := `{"src/": ["b.go", "a.go"], "README.md": true}`
+	got, err := Format([]byte(src), Options{Style: StyleDict, Format: spec.FormatJSON})
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	want := `{
+  "README.md": true,
+  "src/": {
+    "a.go": true,
+    "b.go": true
+  }
+}
+`
+	if string(got) != want {
+		t.Fatalf("Format mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatRefusesUnsupportedSpecs(t *testing.T) {
+	tests := map[string]string{
+		"invalid DSL": "a.txt: false\n",
+		"schema":      "type: object\nproperties: {}\n",
+		"alias":       "a/: &shared\n  x.txt: true\nb/: *shared\n",
+	}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Format([]byte(src), Options{Format: spec.FormatYAML}); err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+	if _, err := Format([]byte("a.txt: true\n"), Options{Style: "tree", Format: spec.FormatYAML}); err == nil || !strings.Contains(err.Error(), "unknown style") {
+		t.Fatalf("expected an unknown style error, got %v", err)
+	}
+}
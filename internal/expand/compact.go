@@ -0,0 +1,355 @@
+package expand
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// CompactIssue reports a part of a schema that Compact could not express in
+// the DSL and kept as a raw schema instead.
+type CompactIssue struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Compact turns a JSON Schema back into the DSL. It recognizes the shapes
+// ExpandDSL produces; any entry it cannot express is kept verbatim as
+// {rawSchema: ...} and reported. Every DSL entry is checked by expanding it
+// again, so ExpandDSL of the result equals the input schema up to the order
+// of "required" lists.
+func Compact(schema map[string]any) (map[string]any, []CompactIssue) {
+	rest := make(map[string]any, len(schema))
+	for key, value := range schema {
+		rest[key] = value
+	}
+	vars, hasVars := rest[VarsKeyword].(map[string]any)
+	if hasVars {
+		delete(rest, VarsKeyword)
+	}
+
+	var issues []CompactIssue
+	dsl, reason := compactDirEntry("", rest, &issues)
+	if reason != "" {
+		issues = append(issues, CompactIssue{Path: ".", Reason: reason})
+		dsl = map[string]any{RawSchemaKey: rest}
+	}
+	if hasVars {
+		dsl[VarsKey] = vars
+	}
+	return dsl, issues
+}
+
+// compactEntry compacts the schema of the entry name in the directory at
+// dir, falling back to a raw schema.
+func compactEntry(dir, name string, schema any, issues *[]CompactIssue) any {
+	path := dir + name
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		*issues = append(*issues, CompactIssue{Path: path, Reason: "schema is not an object"})
+		return map[string]any{RawSchemaKey: map[string]any{}}
+	}
+
+	var value any
+	var reason string
+	if strings.HasSuffix(name, "/") {
+		value, reason = compactDirEntry(path, obj, issues)
+	} else {
+		value, reason = compactFileEntry(name, obj)
+	}
+	if reason != "" {
+		*issues = append(*issues, CompactIssue{Path: path, Reason: reason})
+		return map[string]any{RawSchemaKey: obj}
+	}
+	return value
+}
+
+// compactDirEntry compacts a directory schema, reporting why it cannot when
+// it does not have the shape expandDir produces. Issues for its entries are
+// only added once the directory itself is expressible.
+func compactDirEntry(path string, schema map[string]any, issues *[]CompactIssue) (map[string]any, string) {
+	var local []CompactIssue
+	dsl := map[string]any{}
+	for _, key := range sortedKeys(schema) {
+		value := schema[key]
+		switch key {
+		case "type":
+			if value != "object" {
+				return nil, fmt.Sprintf("directory type %v is not object", value)
+			}
+		case "required", "allOf":
+			// Implied by the entries; checked by re-expanding below.
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return nil, "properties is not an object"
+			}
+			for _, name := range sortedKeys(props) {
+				dsl[name] = compactEntry(path, name, props[name], &local)
+			}
+		case "patternProperties":
+			patterns, ok := value.(map[string]any)
+			if !ok {
+				return nil, "patternProperties is not an object"
+			}
+			for _, pattern := range sortedKeys(patterns) {
+				glob, ok := RegexToGlob(pattern)
+				if !ok {
+					return nil, fmt.Sprintf("pattern %q has no glob equivalent", pattern)
+				}
+				dsl[glob] = compactGlob(path, glob, patterns[pattern], &local)
+			}
+		case SeverityKeyword, CopyFromKeyword:
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Sprintf("%s is not a string", key)
+			}
+			dsl[dslAnnotation(key)] = s
+		default:
+			return nil, fmt.Sprintf("unsupported directory keyword %q", key)
+		}
+	}
+
+	expanded, err := expandDirectoryValue(path, parsedEntry(path, dsl))
+	if err != nil || !sameSchema(expanded, schema) {
+		return nil, "directory does not match the shape expand produces"
+	}
+	*issues = append(*issues, local...)
+	return dsl, ""
+}
+
+// compactGlob compacts the schema of a glob entry, moving its default name
+// back into the DSL value.
+func compactGlob(dir, glob string, schema any, issues *[]CompactIssue) any {
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		return compactEntry(dir, glob, schema, issues)
+	}
+	name, ok := obj[DefaultKeyword].(string)
+	if !ok {
+		return compactEntry(dir, glob, schema, issues)
+	}
+	rest := make(map[string]any, len(obj)-1)
+	for key, value := range obj {
+		if key != DefaultKeyword {
+			rest[key] = value
+		}
+	}
+
+	// Directory globs write their default without the trailing "/".
+	if strings.HasSuffix(glob, "/") {
+		name = strings.TrimSuffix(name, "/")
+	}
+	value := compactEntry(dir, glob, rest, issues)
+	out := map[string]any{"default": name}
+	if desc, ok := value.(map[string]any); ok {
+		for key, v := range desc {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// compactFileEntry compacts a file schema into true or a descriptor,
+// reporting why it cannot when it does not have the shape
+// expandFileDescriptor produces.
+func compactFileEntry(name string, schema map[string]any) (any, string) {
+	desc := map[string]any{}
+	for _, key := range sortedKeys(schema) {
+		value := schema[key]
+		switch key {
+		case "oneOf":
+			if !reflect.DeepEqual(value, existenceOnlyFileSchema()["oneOf"]) {
+				return nil, "oneOf is not the existence-only file schema"
+			}
+		case "type":
+			if value != "object" {
+				return nil, fmt.Sprintf("file type %v is not object", value)
+			}
+		case "required":
+			// Implied by the descriptor; checked by re-expanding below.
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return nil, "properties is not an object"
+			}
+			for _, prop := range sortedKeys(props) {
+				attr, ok := compactAttribute(prop, props[prop])
+				if !ok {
+					return nil, fmt.Sprintf("unsupported file property %q", prop)
+				}
+				desc[prop] = attr
+			}
+		case SeverityKeyword, TemplateKeyword, ContentFromKeyword, VerifyKeyword:
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Sprintf("%s is not a string", key)
+			}
+			desc[dslAnnotation(key)] = s
+		default:
+			return nil, fmt.Sprintf("unsupported file keyword %q", key)
+		}
+	}
+
+	var value any = desc
+	if len(desc) == 0 {
+		value = true
+	}
+	expanded, err := expandFileValue(name, parsedEntry(name, value))
+	if err != nil || !sameSchema(expanded, schema) {
+		return nil, "file does not match the shape expand produces"
+	}
+	return value, ""
+}
+
+// compactAttribute turns the schema of a file descriptor property back into
+// its DSL value.
+func compactAttribute(prop string, schema any) (any, bool) {
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	switch prop {
+	case "content", "sha256", "mode", "symlink":
+		if len(obj) != 1 {
+			return nil, false
+		}
+		s, ok := obj["const"].(string)
+		return s, ok
+	case "size":
+		if c, ok := obj["const"]; ok {
+			if len(obj) != 1 {
+				return nil, false
+			}
+			return integerValue(c)
+		}
+		if obj["type"] != "integer" {
+			return nil, false
+		}
+		bounds := map[string]any{}
+		for key, value := range obj {
+			var name string
+			switch key {
+			case "type":
+				continue
+			case "minimum":
+				name = "min"
+			case "maximum":
+				name = "max"
+			default:
+				return nil, false
+			}
+			n, ok := integerValue(value)
+			if !ok {
+				return nil, false
+			}
+			bounds[name] = n
+		}
+		return bounds, true
+	default:
+		return nil, false
+	}
+}
+
+// integerValue returns a whole JSON number as a float64, the type a loaded
+// spec would carry.
+func integerValue(value any) (any, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, v == math.Trunc(v)
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	default:
+		return nil, false
+	}
+}
+
+// dslAnnotation returns the DSL key for an annotation keyword.
+func dslAnnotation(keyword string) string {
+	switch keyword {
+	case SeverityKeyword:
+		return "severity"
+	case CopyFromKeyword:
+		return "copyFrom"
+	case TemplateKeyword:
+		return "template"
+	case ContentFromKeyword:
+		return "contentFrom"
+	case VerifyKeyword:
+		return "verify"
+	default:
+		return keyword
+	}
+}
+
+// parsedEntry runs a compacted entry through the DSL parser, as ExpandDSL
+// would. Parse errors surface when the entry is expanded.
+func parsedEntry(name string, value any) any {
+	parsed, err := parseValue(name, value, ParseOptions{})
+	if err != nil {
+		return nil
+	}
+	return parsed
+}
+
+// sameSchema reports whether two schemas are equal once encoded, so integer
+// and float numbers and the order of "required" lists do not matter.
+func sameSchema(a, b map[string]any) bool {
+	encodedA, errA := json.Marshal(Canonicalize(a))
+	encodedB, errB := json.Marshal(Canonicalize(b))
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+// RegexToGlob inverts GlobToRegex for the patterns it produces.
+func RegexToGlob(pattern string) (string, bool) {
+	if !strings.HasPrefix(pattern, "^") || !strings.HasSuffix(pattern, "$") || len(pattern) < 2 {
+		return "", false
+	}
+	body := pattern[1 : len(pattern)-1]
+	var buf strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch c {
+		case '.':
+			if i+1 < len(body) && body[i+1] == '*' {
+				buf.WriteByte('*')
+				i++
+			} else {
+				buf.WriteByte('?')
+			}
+		case '\\':
+			if i+1 >= len(body) {
+				return "", false
+			}
+			i++
+			buf.WriteByte(body[i])
+		case '[':
+			j := strings.IndexByte(body[i+1:], ']')
+			if j < 0 {
+				return "", false
+			}
+			class := body[i : i+j+2]
+			if strings.HasPrefix(class, "[^") {
+				class = "[!" + class[2:]
+			}
+			buf.WriteString(class)
+			i += j + 1
+		case '*', '+', '?', '^', '$', '(', ')', '{', '}', '|', ']':
+			return "", false
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	glob := buf.String()
+	if !IsGlobPattern(glob) {
+		return "", false
+	}
+	if back, err := GlobToRegex(glob); err != nil || back != pattern {
+		return "", false
+	}
+	return glob, true
+}
@@ -0,0 +1,155 @@
+package expand
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// loadedJSON round-trips value through JSON so it has the types a loaded
+// spec or schema would have.
+func loadedJSON(t *testing.T, value any) map[string]any {
+	t.Helper()
+	raw, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return out
+}
+
+func TestCompactRoundTrips(t *testing.T) {
+	tests := map[string]map[string]any{
+		"plain": {
+			"README.md": true,
+			"src/": map[string]any{
+				"main.go": true,
+				"*.go":    true,
+			},
+			"empty/": map[string]any{},
+		},
+		"descriptors": {
+			"run.sh":    map[string]any{"content": "#!/bin/sh\n", "mode": "0755"},
+			"data.bin":  map[string]any{"size": map[string]any{"min": 1, "max": 1024}},
+			"fixed.bin": map[string]any{"size": 12, "sha256": "abc"},
+			"link":      map[string]any{"symlink": "run.sh"},
+		},
+		"annotations": {
+			"vars":     map[string]any{"name": "demo"},
+			"severity": "warning",
+			"NOTES":    map[string]any{"severity": "warning"},
+			"main.go":  map[string]any{"template": "package {{ .name }}\n"},
+			"LICENSE":  map[string]any{"contentFrom": "LICENSE.tmpl", "verify": "sha256"},
+			"vendor/":  map[string]any{"copyFrom": "../vendor"},
+			"cmd/": map[string]any{
+				"*/":        map[string]any{"default": "app", "main.go": true},
+				"[!_]*.txt": map[string]any{"default": "a.txt", "size": 0},
+			},
+		},
+	}
+	for name, dsl := range tests {
+		t.Run(name, func(t *testing.T) {
+			schema, err := ExpandDSL(loadedJSON(t, dsl))
+			if err != nil {
+				t.Fatalf("ExpandDSL: %v", err)
+			}
+			loaded := loadedJSON(t, schema)
+
+			compacted, issues := Compact(loaded)
+			if len(issues) != 0 {
+				t.Fatalf("unexpected issues: %#v", issues)
+			}
+			again, err := ExpandDSL(loadedJSON(t, compacted))
+			if err != nil {
+				t.Fatalf("ExpandDSL of compacted DSL: %v\n%#v", err, compacted)
+			}
+			if !reflect.DeepEqual(loadedJSON(t, Canonicalize(again)), loadedJSON(t, Canonicalize(loaded))) {
+				t.Fatalf("round trip mismatch:\ngot:  %#v\nwant: %#v", again, loaded)
+			}
+			if !reflect.DeepEqual(loadedJSON(t, compacted), loadedJSON(t, dsl)) {
+				t.Fatalf("compacted DSL mismatch:\ngot:  %#v\nwant: %#v", compacted, dsl)
+			}
+		})
+	}
+}
+
+func TestCompactKeepsRawSchemas(t *testing.T) {
+	schema := loadedJSON(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"README.md": map[string]any{"oneOf": []any{map[string]any{"const": true}, map[string]any{"type": "object"}}},
+			// Not a shape expand produces.
+			"config.json": map[string]any{"type": "object", "minProperties": 1},
+			"lib/": map[string]any{
+				"type":                 "object",
+				"required":             []any{},
+				"additionalProperties": false,
+			},
+		},
+		"required": []any{"README.md", "config.json", "lib/"},
+	})
+
+	compacted, issues := Compact(schema)
+	want := map[string]any{
+		"README.md":   true,
+		"config.json": map[string]any{RawSchemaKey: schema["properties"].(map[string]any)["config.json"]},
+		"lib/":        map[string]any{RawSchemaKey: schema["properties"].(map[string]any)["lib/"]},
+	}
+	if !reflect.DeepEqual(compacted, want) {
+		t.Fatalf("Compact mismatch:\ngot:  %#v\nwant: %#v", compacted, want)
+	}
+	wantIssues := []CompactIssue{
+		{Path: "config.json", Reason: `unsupported file keyword "minProperties"`},
+		{Path: "lib/", Reason: `unsupported directory keyword "additionalProperties"`},
+	}
+	if !reflect.DeepEqual(issues, wantIssues) {
+		t.Fatalf("issues mismatch:\ngot:  %#v\nwant: %#v", issues, wantIssues)
+	}
+
+	again, err := ExpandDSL(compacted)
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+	if !reflect.DeepEqual(Canonicalize(again), Canonicalize(schema)) {
+		t.Fatalf("raw round trip mismatch:\ngot:  %#v\nwant: %#v", again, schema)
+	}
+}
+
+func TestCompactRawRoot(t *testing.T) {
+	schema := map[string]any{"type": "object", "maxProperties": 3}
+	compacted, issues := Compact(schema)
+	if !reflect.DeepEqual(compacted, map[string]any{RawSchemaKey: schema}) {
+		t.Fatalf("expected raw root, got %#v", compacted)
+	}
+	if len(issues) != 1 || issues[0].Path != "." {
+		t.Fatalf("expected one root issue, got %#v", issues)
+	}
+	again, err := ExpandDSL(compacted)
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+	if !reflect.DeepEqual(again, schema) {
+		t.Fatalf("raw root mismatch: %#v", again)
+	}
+}
+
+func TestRegexToGlob(t *testing.T) {
+	for _, glob := range []string{"*.go", "test_?.py", "[!_]*", "[abc].txt", "*/", "a+b(*).{x}"} {
+		pattern, err := GlobToRegex(glob)
+		if err != nil {
+			t.Fatalf("GlobToRegex(%q): %v", glob, err)
+		}
+		got, ok := RegexToGlob(pattern)
+		if !ok || got != glob {
+			t.Fatalf("RegexToGlob(%q) = %q, %v; want %q", pattern, got, ok, glob)
+		}
+	}
+	for _, pattern := range []string{"^foo$", "^a+$", "^(a|b)\\.go$", "foo"} {
+		if got, ok := RegexToGlob(pattern); ok {
+			t.Fatalf("RegexToGlob(%q) = %q; want no glob", pattern, got)
+		}
+	}
+}
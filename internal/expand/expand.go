@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -12,7 +13,26 @@ func ExpandDSL(root any) (map[string]any, error) {
 	if err != nil {
 		return nil, err
 	}
-	return expandDir(parsed)
+	vars, hasVars := parsed[VarsKey].(map[string]any)
+	if hasVars {
+		delete(parsed, VarsKey)
+	}
+	var result map[string]any
+	if raw, ok := rawSchemaValue(parsed); ok {
+		result = make(map[string]any, len(raw)+1)
+		for key, value := range raw {
+			result[key] = value
+		}
+	} else {
+		result, err = expandDir(parsed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasVars {
+		result[VarsKeyword] = vars
+	}
+	return result, nil
 }
 
 func expandDir(node map[string]any) (map[string]any, error) {
@@ -25,19 +45,28 @@ func expandDir(node map[string]any) (map[string]any, error) {
 	properties := make(map[string]any)
 	patternProperties := make(map[string]any)
 	required := make([]any, 0)
+	annotations := map[string]any{}
 
 	for _, key := range keys {
 		value := node[key]
+		if keyword, val, ok := directoryAnnotation(key, value); ok {
+			annotations[keyword] = val
+			continue
+		}
 		var schema map[string]any
 		var err error
 
 		if strings.HasSuffix(key, "/") {
 			// Directory - check if it's a pattern
 			dirName := key
-			if isGlobPattern(dirName) {
+			if IsGlobPattern(dirName) {
 				// Pattern directory: strip trailing / for pattern, convert to regex
 				patternBase := strings.TrimSuffix(dirName, "/")
-				regexPattern, err := globToRegex(patternBase + "/")
+				regexPattern, err := GlobToRegex(patternBase + "/")
+				if err != nil {
+					return nil, err
+				}
+				value, defaultName, err := splitGlobDefault(key, value, regexPattern)
 				if err != nil {
 					return nil, err
 				}
@@ -45,6 +74,9 @@ func expandDir(node map[string]any) (map[string]any, error) {
 				if err != nil {
 					return nil, err
 				}
+				if defaultName != "" {
+					schema[DefaultKeyword] = defaultName
+				}
 				patternProperties[regexPattern] = schema
 			} else {
 				schema, err = expandDirectoryValue(key, value)
@@ -56,8 +88,12 @@ func expandDir(node map[string]any) (map[string]any, error) {
 			}
 		} else {
 			// File - check if it's a pattern
-			if isGlobPattern(key) {
-				regexPattern, err := globToRegex(key)
+			if IsGlobPattern(key) {
+				regexPattern, err := GlobToRegex(key)
+				if err != nil {
+					return nil, err
+				}
+				value, defaultName, err := splitGlobDefault(key, value, regexPattern)
 				if err != nil {
 					return nil, err
 				}
@@ -65,6 +101,9 @@ func expandDir(node map[string]any) (map[string]any, error) {
 				if err != nil {
 					return nil, err
 				}
+				if defaultName != "" {
+					schema[DefaultKeyword] = defaultName
+				}
 				patternProperties[regexPattern] = schema
 			} else {
 				schema, err = expandFileValue(key, value)
@@ -81,7 +120,10 @@ func expandDir(node map[string]any) (map[string]any, error) {
 		"type": "object",
 	}
 
-	if len(properties) > 0 {
+	// A copied directory may list no entries of its own; its contents come
+	// from the source directory.
+	_, copied := annotations[CopyFromKeyword]
+	if len(properties) > 0 || (copied && len(patternProperties) == 0) {
 		result["properties"] = properties
 	}
 	if len(patternProperties) > 0 {
@@ -92,6 +134,9 @@ func expandDir(node map[string]any) (map[string]any, error) {
 	} else {
 		result["required"] = []any{}
 	}
+	for keyword, val := range annotations {
+		result[keyword] = val
+	}
 
 	// Require at least one matching entry for each glob pattern.
 	//
@@ -143,6 +188,9 @@ func expandDir(node map[string]any) (map[string]any, error) {
 }
 
 func expandDirectoryValue(key string, value any) (map[string]any, error) {
+	if raw, ok := rawSchemaValue(value); ok {
+		return raw, nil
+	}
 	switch v := value.(type) {
 	case nil:
 		return expandDir(map[string]any{})
@@ -154,6 +202,9 @@ func expandDirectoryValue(key string, value any) (map[string]any, error) {
 }
 
 func expandFileValue(key string, value any) (map[string]any, error) {
+	if raw, ok := rawSchemaValue(value); ok {
+		return raw, nil
+	}
 	if value == nil {
 		return existenceOnlyFileSchema(), nil
 	}
@@ -170,6 +221,16 @@ func expandFileValue(key string, value any) (map[string]any, error) {
 	return nil, fmt.Errorf("file %q must be true or object", key)
 }
 
+// rawSchemaValue returns the schema of a raw entry ({rawSchema: {...}}).
+func rawSchemaValue(value any) (map[string]any, bool) {
+	obj, ok := value.(map[string]any)
+	if !ok || len(obj) != 1 {
+		return nil, false
+	}
+	raw, ok := obj[RawSchemaKey].(map[string]any)
+	return raw, ok
+}
+
 // existenceOnlyFileSchema returns a schema that matches both:
 // - true (when no attributes requested)
 // - object (when global attributes like content are included)
@@ -182,16 +243,115 @@ func existenceOnlyFileSchema() map[string]any {
 	}
 }
 
+// Schema annotations emitted for DSL features that have no JSON Schema
+// equivalent.
+const (
+	SeverityKeyword    = "x-dirschema-severity"
+	TemplateKeyword    = "x-dirschema-template"
+	VarsKeyword        = "x-dirschema-vars"
+	ContentFromKeyword = "x-dirschema-content-from"
+	CopyFromKeyword    = "x-dirschema-copy-from"
+	VerifyKeyword      = "x-dirschema-verify"
+	DefaultKeyword     = "x-dirschema-default"
+)
+
+// directoryAnnotations maps DSL keys that annotate the enclosing directory
+// (when given a string value) to their schema keywords.
+var directoryAnnotations = map[string]string{
+	"severity": SeverityKeyword,
+	"copyFrom": CopyFromKeyword,
+}
+
+// directoryAnnotation reports whether a DSL entry annotates its directory
+// rather than naming a file or subdirectory.
+func directoryAnnotation(key string, value any) (keyword, val string, ok bool) {
+	keyword, ok = directoryAnnotations[key]
+	if !ok {
+		return "", "", false
+	}
+	val, ok = value.(string)
+	return keyword, val, ok
+}
+
+// splitGlobDefault removes the default: name from a glob entry's value.
+// The name must match the glob; directory globs may omit its trailing "/".
+// A value left empty by the removal becomes an existence-only entry.
+func splitGlobDefault(key string, value any, regexPattern string) (any, string, error) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return value, "", nil
+	}
+	raw, ok := obj["default"]
+	if !ok {
+		return value, "", nil
+	}
+	name, ok := raw.(string)
+	if !ok || name == "" {
+		return nil, "", fmt.Errorf("glob %q default must be a non-empty string", key)
+	}
+	if strings.HasSuffix(key, "/") && !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+	if !regexp.MustCompile(regexPattern).MatchString(name) {
+		return nil, "", fmt.Errorf("glob %q default %q does not match the pattern", key, name)
+	}
+
+	rest := make(map[string]any, len(obj)-1)
+	for k, v := range obj {
+		if k != "default" {
+			rest[k] = v
+		}
+	}
+	if len(rest) == 0 {
+		return nil, name, nil
+	}
+	return rest, name, nil
+}
+
 func expandFileDescriptor(key string, obj map[string]any) (map[string]any, error) {
+	if keyword, sev, ok := directoryAnnotation("severity", obj["severity"]); ok {
+		rest := make(map[string]any, len(obj)-1)
+		for k, v := range obj {
+			if k != "severity" {
+				rest[k] = v
+			}
+		}
+		var schema map[string]any
+		var err error
+		if len(rest) == 0 {
+			schema = existenceOnlyFileSchema()
+		} else {
+			schema, err = expandFileDescriptor(key, rest)
+			if err != nil {
+				return nil, err
+			}
+		}
+		schema[keyword] = sev
+		return schema, nil
+	}
+
 	// Check for mutually exclusive properties
 	_, hasSymlink := obj["symlink"]
 	_, hasContent := obj["content"]
 	_, hasSize := obj["size"]
 	_, hasSha256 := obj["sha256"]
+	_, hasTemplate := obj["template"]
+	_, hasContentFrom := obj["contentFrom"]
+	_, hasVerify := obj["verify"]
+	_, hasMode := obj["mode"]
 
 	// Symlink is exclusive with everything else
-	if hasSymlink && (hasContent || hasSize || hasSha256) {
-		return nil, fmt.Errorf("file %q: symlink cannot be combined with content/size/sha256", key)
+	if hasSymlink && (hasContent || hasSize || hasSha256 || hasMode || hasTemplate || hasContentFrom) {
+		return nil, fmt.Errorf("file %q: symlink cannot be combined with content/size/sha256/mode/template/contentFrom", key)
+	}
+	if hasTemplate && hasContent {
+		return nil, fmt.Errorf("file %q: template cannot be combined with content", key)
+	}
+	if hasContentFrom && (hasContent || hasTemplate) {
+		return nil, fmt.Errorf("file %q: contentFrom cannot be combined with content/template", key)
+	}
+	if hasVerify && !hasContentFrom {
+		return nil, fmt.Errorf("file %q: verify requires contentFrom", key)
 	}
 
 	// Symlink-only case
@@ -209,8 +369,10 @@ func expandFileDescriptor(key string, obj map[string]any) (map[string]any, error
 		}, nil
 	}
 
-	// Regular file with content/size/sha256 (can be combined)
-	if hasContent || hasSize || hasSha256 {
+	// Regular file with content/size/sha256/template/contentFrom (can be
+	// combined). Templates and content sources leave content unconstrained
+	// here; it is pinned once variables and the spec location are known.
+	if hasContent || hasSize || hasSha256 || hasMode || hasTemplate || hasContentFrom {
 		props := make(map[string]any)
 		required := make([]any, 0)
 
@@ -241,12 +403,43 @@ func expandFileDescriptor(key string, obj map[string]any) (map[string]any, error
 			required = append(required, "sha256")
 		}
 
+		if hasMode {
+			mode, err := parseMode(key, obj["mode"])
+			if err != nil {
+				return nil, err
+			}
+			props["mode"] = map[string]any{"const": mode}
+			required = append(required, "mode")
+		}
+
 		sortAnyStrings(required)
-		return map[string]any{
+		result := map[string]any{
 			"type":       "object",
 			"properties": props,
 			"required":   required,
-		}, nil
+		}
+		if hasTemplate {
+			tmpl, ok := obj["template"].(string)
+			if !ok {
+				return nil, fmt.Errorf("file %q template must be string", key)
+			}
+			result[TemplateKeyword] = tmpl
+		}
+		if hasContentFrom {
+			src, ok := obj["contentFrom"].(string)
+			if !ok || src == "" {
+				return nil, fmt.Errorf("file %q contentFrom must be a non-empty string", key)
+			}
+			result[ContentFromKeyword] = src
+		}
+		if hasVerify {
+			verify, ok := obj["verify"].(string)
+			if !ok || (verify != "sha256" && verify != "content") {
+				return nil, fmt.Errorf("file %q verify must be sha256 or content", key)
+			}
+			result[VerifyKeyword] = verify
+		}
+		return result, nil
 	}
 
 	// List unsupported keys for better error message
@@ -258,6 +451,20 @@ func expandFileDescriptor(key string, obj map[string]any) (map[string]any, error
 	return nil, fmt.Errorf("file %q has unsupported descriptor keys: %v", key, keys)
 }
 
+// parseMode accepts permission bits as an octal string ("644" or "0644") and
+// returns them in the four-digit form the walker records.
+func parseMode(key string, mode any) (string, error) {
+	s, ok := mode.(string)
+	if !ok {
+		return "", fmt.Errorf("file %q mode must be an octal string like \"0644\"", key)
+	}
+	bits, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || len(s) < 3 || len(s) > 4 || bits > 0o777 {
+		return "", fmt.Errorf("file %q mode %q must be octal permission bits like \"0644\"", key, s)
+	}
+	return fmt.Sprintf("%04o", bits), nil
+}
+
 func expandSizeConstraint(key string, size any) (map[string]any, error) {
 	switch v := size.(type) {
 	case float64:
@@ -298,15 +505,15 @@ func sortAnyStrings(s []any) {
 	})
 }
 
-// isGlobPattern returns true if the key contains glob characters (* ? [)
-func isGlobPattern(key string) bool {
+// IsGlobPattern returns true if the key contains glob characters (* ? [)
+func IsGlobPattern(key string) bool {
 	return strings.ContainsAny(key, "*?[")
 }
 
-// globToRegex converts a simple glob pattern to a regex pattern.
+// GlobToRegex converts a simple glob pattern to a regex pattern.
 // Supports: * (any chars), ? (single char), [...] (character class)
 // The result is anchored with ^ and $.
-func globToRegex(glob string) (string, error) {
+func GlobToRegex(glob string) (string, error) {
 	var buf strings.Builder
 	buf.WriteString("^")
 
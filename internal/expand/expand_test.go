@@ -211,6 +211,41 @@ func TestExpandSha256DSL(t *testing.T) {
 	}
 }
 
+func TestExpandModeDSL(t *testing.T) {
+	dsl := map[string]any{
+		"run.sh": map[string]any{"mode": "755"},
+	}
+
+	got, err := ExpandDSL(dsl)
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"run.sh": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"mode": map[string]any{"const": "0755"},
+				},
+				"required": []any{"mode"},
+			},
+		},
+		"required": []any{"run.sh"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("schema mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	for _, bad := range []any{"rwxr-xr-x", "07777", float64(755)} {
+		if _, err := ExpandDSL(map[string]any{"run.sh": map[string]any{"mode": bad}}); err == nil {
+			t.Fatalf("expected error for mode %#v", bad)
+		}
+	}
+}
+
 func TestExpandSizeExactDSL(t *testing.T) {
 	dsl := map[string]any{
 		"file.dat": map[string]any{"size": float64(1024)},
@@ -424,6 +459,162 @@ func TestExpandDirectoryGlobPattern(t *testing.T) {
 	}
 }
 
+func TestExpandSeverityDSL(t *testing.T) {
+	dsl := []any{
+		map[string]any{"docs/": []any{
+			map[string]any{"severity": "warning"},
+			"index.md",
+		}},
+		map[string]any{"NOTES.md": map[string]any{"severity": "warning"}},
+		map[string]any{"severity": true},
+	}
+
+	got, err := ExpandDSL(dsl)
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+
+	existenceSchema := map[string]any{
+		"oneOf": []any{
+			map[string]any{"const": true},
+			map[string]any{"type": "object"},
+		},
+	}
+	warnedFile := existenceOnlyFileSchema()
+	warnedFile[SeverityKeyword] = "warning"
+
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"docs/": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"index.md": existenceSchema,
+				},
+				"required":      []any{"index.md"},
+				SeverityKeyword: "warning",
+			},
+			"NOTES.md": warnedFile,
+			"severity": existenceSchema,
+		},
+		"required": []any{"NOTES.md", "docs/", "severity"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("schema mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestExpandRejectsUnknownSeverity(t *testing.T) {
+	_, err := ExpandDSL(map[string]any{"a.txt": map[string]any{"severity": "info"}})
+	if err == nil {
+		t.Fatalf("expected error for unknown severity")
+	}
+}
+
+func TestExpandTemplateAndVars(t *testing.T) {
+	dsl := []any{
+		map[string]any{"vars": map[string]any{"name": "svc"}},
+		map[string]any{"go.mod": map[string]any{"template": "module {{.name}}\n"}},
+	}
+
+	got, err := ExpandDSL(dsl)
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"go.mod": map[string]any{
+				"type":          "object",
+				"properties":    map[string]any{},
+				"required":      []any{},
+				TemplateKeyword: "module {{.name}}\n",
+			},
+		},
+		"required":  []any{"go.mod"},
+		VarsKeyword: map[string]any{"name": "svc"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("schema mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	if _, err := ExpandDSL(map[string]any{"a": map[string]any{"template": "x", "content": "y"}}); err == nil {
+		t.Fatalf("expected error combining template and content")
+	}
+}
+
+func TestExpandContentSources(t *testing.T) {
+	dsl := map[string]any{
+		"LICENSE": map[string]any{"contentFrom": "texts/MIT", "verify": "content"},
+		"ci/": map[string]any{
+			"copyFrom":  "templates/ci",
+			"build.yml": true,
+		},
+	}
+
+	got, err := ExpandDSL(dsl)
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+
+	props := got["properties"].(map[string]any)
+	license := props["LICENSE"].(map[string]any)
+	if license[ContentFromKeyword] != "texts/MIT" || license[VerifyKeyword] != "content" {
+		t.Fatalf("unexpected LICENSE schema: %#v", license)
+	}
+	ci := props["ci/"].(map[string]any)
+	if ci[CopyFromKeyword] != "templates/ci" {
+		t.Fatalf("unexpected ci/ schema: %#v", ci)
+	}
+	if _, ok := ci["properties"].(map[string]any)["copyFrom"]; ok {
+		t.Fatalf("copyFrom should not be expanded as an entry: %#v", ci)
+	}
+
+	invalid := []map[string]any{
+		{"a": map[string]any{"contentFrom": "x", "content": "y"}},
+		{"a": map[string]any{"contentFrom": "x", "symlink": "y"}},
+		{"a": map[string]any{"contentFrom": ""}},
+		{"a": map[string]any{"sha256": "00", "verify": "sha256"}},
+		{"a": map[string]any{"contentFrom": "x", "verify": "mtime"}},
+	}
+	for _, dsl := range invalid {
+		if _, err := ExpandDSL(dsl); err == nil {
+			t.Fatalf("expected error for %#v", dsl)
+		}
+	}
+}
+
+func TestExpandGlobDefault(t *testing.T) {
+	dsl := map[string]any{
+		"*.go":   map[string]any{"default": "main.go"},
+		"cmd-*/": map[string]any{"default": "cmd-app", "main.go": true},
+	}
+
+	got, err := ExpandDSL(dsl)
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+
+	patterns := got["patternProperties"].(map[string]any)
+	goFiles := patterns["^.*\\.go$"].(map[string]any)
+	if goFiles[DefaultKeyword] != "main.go" {
+		t.Fatalf("unexpected *.go schema: %#v", goFiles)
+	}
+	if _, ok := goFiles["oneOf"]; !ok {
+		t.Fatalf("expected existence-only schema for *.go: %#v", goFiles)
+	}
+	cmdDirs := patterns["^cmd-.*/$"].(map[string]any)
+	if cmdDirs[DefaultKeyword] != "cmd-app/" {
+		t.Fatalf("unexpected cmd-*/ schema: %#v", cmdDirs)
+	}
+
+	if _, err := ExpandDSL(map[string]any{"*.go": map[string]any{"default": "main.py"}}); err == nil {
+		t.Fatalf("expected error for default not matching the glob")
+	}
+}
+
 func TestGlobToRegex(t *testing.T) {
 	tests := []struct {
 		glob  string
@@ -440,12 +631,12 @@ func TestGlobToRegex(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.glob, func(t *testing.T) {
-			got, err := globToRegex(tc.glob)
+			got, err := GlobToRegex(tc.glob)
 			if err != nil {
-				t.Fatalf("globToRegex(%q): %v", tc.glob, err)
+				t.Fatalf("GlobToRegex(%q): %v", tc.glob, err)
 			}
 			if got != tc.regex {
-				t.Fatalf("globToRegex(%q) = %q, want %q", tc.glob, got, tc.regex)
+				t.Fatalf("GlobToRegex(%q) = %q, want %q", tc.glob, got, tc.regex)
 			}
 		})
 	}
@@ -467,9 +658,9 @@ func TestIsGlobPattern(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.key, func(t *testing.T) {
-			got := isGlobPattern(tc.key)
+			got := IsGlobPattern(tc.key)
 			if got != tc.isGlob {
-				t.Fatalf("isGlobPattern(%q) = %v, want %v", tc.key, got, tc.isGlob)
+				t.Fatalf("IsGlobPattern(%q) = %v, want %v", tc.key, got, tc.isGlob)
 			}
 		})
 	}
@@ -513,6 +704,12 @@ func TestExpandOutputPassesMetaSchema(t *testing.T) {
 				"data.bin": map[string]any{"sha256": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
 			},
 		},
+		{
+			name: "mode",
+			dsl: map[string]any{
+				"run.sh": map[string]any{"mode": "0755"},
+			},
+		},
 		{
 			name: "size exact",
 			dsl: map[string]any{
@@ -592,6 +789,38 @@ func TestExpandOutputPassesMetaSchema(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "severity annotations",
+			dsl: map[string]any{
+				"severity": "warning",
+				"docs/": map[string]any{
+					"severity": "error",
+					"index.md": map[string]any{"severity": "warning"},
+				},
+				"CHANGELOG.md": map[string]any{"content": "x", "severity": "warning"},
+			},
+		},
+		{
+			name: "template with vars",
+			dsl: map[string]any{
+				"vars":   map[string]any{"name": "svc"},
+				"go.mod": map[string]any{"template": "module {{.name}}\n"},
+			},
+		},
+		{
+			name: "content sources",
+			dsl: map[string]any{
+				"LICENSE": map[string]any{"contentFrom": "LICENSE", "verify": "sha256"},
+				"ci/":     map[string]any{"copyFrom": "templates/ci"},
+			},
+		},
+		{
+			name: "glob defaults",
+			dsl: map[string]any{
+				"*.go":   map[string]any{"default": "main.go"},
+				"cmd-*/": map[string]any{"default": "cmd-app", "main.go": true},
+			},
+		},
 		{
 			name: "mixed patterns and literals",
 			dsl: map[string]any{
@@ -36,3 +36,45 @@ func dirList(node map[string]any) []any {
 	}
 	return entries
 }
+
+// Canonicalize returns a copy of schema in the form expand prints it: object
+// keys are sorted by the encoders, and "required" lists are sorted here, so
+// equivalent schemas print identically.
+func Canonicalize(schema map[string]any) map[string]any {
+	out, _ := canonicalValue(schema).(map[string]any)
+	return out
+}
+
+func canonicalValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, child := range v {
+			out[key] = canonicalValue(child)
+			if key != "required" {
+				continue
+			}
+			if names, ok := out[key].([]any); ok && allStrings(names) {
+				sortAnyStrings(names)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			out[i] = canonicalValue(child)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func allStrings(values []any) bool {
+	for _, v := range values {
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package expand
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalizeSortsRequired(t *testing.T) {
+	schema := map[string]any{
+		"required": []any{"b", "a"},
+		"properties": map[string]any{
+			"dir/": map[string]any{"required": []any{"z", "y"}},
+			// A file named "required" is a schema, not a name list.
+			"required": map[string]any{"const": true},
+		},
+		"enum": []any{"b", "a"},
+	}
+
+	got := Canonicalize(schema)
+	want := map[string]any{
+		"required": []any{"a", "b"},
+		"properties": map[string]any{
+			"dir/":     map[string]any{"required": []any{"y", "z"}},
+			"required": map[string]any{"const": true},
+		},
+		"enum": []any{"b", "a"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Canonicalize mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+	if !reflect.DeepEqual(schema["required"], []any{"b", "a"}) {
+		t.Fatalf("Canonicalize modified its input: %#v", schema["required"])
+	}
+}
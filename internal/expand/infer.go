@@ -0,0 +1,284 @@
+package expand
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// InferOptions sets how eagerly InferGlobs generalizes a tree.
+type InferOptions struct {
+	// MinFiles is how many sibling files must share a name pattern before
+	// they are collapsed into a glob entry.
+	MinFiles int
+	// MinDirs is how many sibling directories must share the same structure
+	// before they are factored into a single "*/" entry. All sibling
+	// directories must match, since the entry applies to each of them.
+	MinDirs int
+}
+
+// DefaultInferOptions are the thresholds export uses unless told otherwise.
+var DefaultInferOptions = InferOptions{MinFiles: 3, MinDirs: 3}
+
+// InferGlobs generalizes an instance (as produced by the walker) into a
+// DSL-shaped tree with glob entries. Sibling files sharing a prefix, suffix
+// or extension ("test_*.py", "*_test.go", "*.go") are collapsed into one
+// entry whose descriptor keeps only the attributes all matching files
+// share; a prefix or suffix glob is dropped when an extension glob already
+// matches its files. Symlinks and dotfiles without an extension stay
+// literal.
+func InferGlobs(instance map[string]any, opts InferOptions) map[string]any {
+	out := make(map[string]any, len(instance))
+	dirs := map[string]any{}
+	files := map[string]any{}
+	for key, value := range instance {
+		if strings.HasSuffix(key, "/") {
+			child, _ := value.(map[string]any)
+			dirs[key] = InferGlobs(child, opts)
+			continue
+		}
+		files[key] = value
+	}
+
+	for key, value := range inferDirGlob(dirs, opts) {
+		out[key] = value
+	}
+	for key, value := range inferFileGlobs(files, opts) {
+		out[key] = value
+	}
+	return out
+}
+
+// inferDirGlob factors directories into "*/" when there are enough of them
+// and all have the same structure.
+func inferDirGlob(dirs map[string]any, opts InferOptions) map[string]any {
+	if opts.MinDirs <= 0 || len(dirs) < opts.MinDirs {
+		return dirs
+	}
+	var shape any
+	for _, child := range dirs {
+		if shape == nil {
+			shape = child
+			continue
+		}
+		if !reflect.DeepEqual(shape, child) {
+			return dirs
+		}
+	}
+	return map[string]any{"*/": shape}
+}
+
+func inferFileGlobs(files map[string]any, opts InferOptions) map[string]any {
+	if opts.MinFiles <= 0 || len(files) < opts.MinFiles {
+		return files
+	}
+
+	names := make([]string, 0, len(files))
+	for name, value := range files {
+		if groupable(name, value) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Prefix and suffix patterns are more specific than extensions, so they
+	// claim files first, largest group first.
+	var globs []string
+	unclaimed := map[string]bool{}
+	for _, name := range names {
+		unclaimed[name] = true
+	}
+	claim := func(candidates func(string) []string) {
+		counts := map[string]int{}
+		for _, name := range names {
+			if !unclaimed[name] {
+				continue
+			}
+			for _, glob := range candidates(name) {
+				counts[glob]++
+			}
+		}
+		for _, glob := range byCount(counts) {
+			members := matching(glob, names, unclaimed)
+			if len(members) < opts.MinFiles {
+				continue
+			}
+			globs = append(globs, glob)
+			for _, name := range members {
+				delete(unclaimed, name)
+			}
+		}
+	}
+	claim(affixGlobs)
+	claim(func(name string) []string { return []string{"*" + filepath.Ext(name)} })
+	globs = dropCovered(globs, names)
+	if len(globs) == 0 {
+		return files
+	}
+
+	// A glob constrains every file it matches, so its value is computed over
+	// all of them, but only groupable files are folded into it.
+	out := map[string]any{}
+	absorbed := map[string]bool{}
+	for _, glob := range globs {
+		var values []any
+		for _, name := range matching(glob, sortedKeys(files), nil) {
+			values = append(values, files[name])
+		}
+		for _, name := range matching(glob, names, nil) {
+			absorbed[name] = true
+		}
+		out[glob] = commonValue(values)
+	}
+	for name, value := range files {
+		if !absorbed[name] {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+// groupable reports whether a file can join a glob: it must have an
+// extension and a stem, must not be a symlink, and must not look like a
+// glob itself.
+func groupable(name string, value any) bool {
+	if IsGlobPattern(name) {
+		return false
+	}
+	if desc, ok := value.(map[string]any); ok {
+		if _, ok := desc["symlink"]; ok {
+			return false
+		}
+	}
+	ext := filepath.Ext(name)
+	return ext != "" && ext != name
+}
+
+// dropCovered drops globs whose files another glob matches too, such as
+// "test_*.py" next to "*.py": both would apply to those files, which lint
+// reports as overlapping globs. Of two globs matching the same files the
+// first one is kept.
+func dropCovered(globs, names []string) []string {
+	members := make([]map[string]bool, len(globs))
+	for i, glob := range globs {
+		members[i] = map[string]bool{}
+		for _, name := range matching(glob, names, nil) {
+			members[i][name] = true
+		}
+	}
+	var out []string
+	for i, glob := range globs {
+		covered := false
+		for j := range globs {
+			if j == i || !subset(members[i], members[j]) {
+				continue
+			}
+			if len(members[i]) < len(members[j]) || j < i {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			out = append(out, glob)
+		}
+	}
+	return out
+}
+
+func subset(a, b map[string]bool) bool {
+	for name := range a {
+		if !b[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// affixGlobs returns the prefix and suffix patterns name could belong to:
+// "test_foo.py" gives "test_*.py", "foo_test.go" gives "*_test.go".
+func affixGlobs(name string) []string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	var out []string
+	if i := strings.IndexAny(stem, "_-"); i > 0 && i < len(stem)-1 {
+		out = append(out, stem[:i+1]+"*"+ext)
+	}
+	if i := strings.LastIndexAny(stem, "_-"); i > 0 && i < len(stem)-1 {
+		out = append(out, "*"+stem[i:]+ext)
+	}
+	return out
+}
+
+// byCount orders patterns by how many files proposed them, then by name.
+func byCount(counts map[string]int) []string {
+	out := make([]string, 0, len(counts))
+	for glob := range counts {
+		out = append(out, glob)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if counts[out[i]] != counts[out[j]] {
+			return counts[out[i]] > counts[out[j]]
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// matching returns the names glob matches, limited to those in only when
+// it is non-nil.
+func matching(glob string, names []string, only map[string]bool) []string {
+	pattern, err := GlobToRegex(glob)
+	if err != nil {
+		return nil
+	}
+	re := regexp.MustCompile(pattern)
+	var out []string
+	for _, name := range names {
+		if only != nil && !only[name] {
+			continue
+		}
+		if re.MatchString(name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// commonValue keeps the descriptor attributes every value agrees on, or
+// true when they share none.
+func commonValue(values []any) any {
+	var common map[string]any
+	for i, value := range values {
+		desc, ok := value.(map[string]any)
+		if !ok {
+			return true
+		}
+		if i == 0 {
+			common = make(map[string]any, len(desc))
+			for k, v := range desc {
+				common[k] = v
+			}
+			continue
+		}
+		for k, v := range common {
+			if !reflect.DeepEqual(desc[k], v) {
+				delete(common, k)
+			}
+		}
+	}
+	if len(common) == 0 {
+		return true
+	}
+	return common
+}
+
+func sortedKeys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for key := range m {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
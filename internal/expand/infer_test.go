@@ -0,0 +1,92 @@
+package expand
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInferGlobs(t *testing.T) {
+	mode := func(m string) map[string]any { return map[string]any{"mode": m, "size": int64(1)} }
+	instance := map[string]any{
+		"a.go":       mode("0644"),
+		"b.go":       mode("0644"),
+		"c.go":       mode("0644"),
+		"a_test.go":  mode("0644"),
+		"b_test.go":  mode("0644"),
+		"c_test.go":  mode("0600"),
+		"notes.txt":  true,
+		"link.go":    map[string]any{"symlink": "a.go"},
+		".gitignore": true,
+		"README.md":  true,
+		"CHANGES.md": true,
+		"services/": map[string]any{
+			"api/":  map[string]any{"main.go": true},
+			"auth/": map[string]any{"main.go": true},
+			"web/":  map[string]any{"main.go": true},
+		},
+		"mixed/": map[string]any{
+			"a/": map[string]any{"x": true},
+			"b/": map[string]any{"y": true},
+			"c/": map[string]any{"x": true},
+		},
+	}
+
+	got := InferGlobs(instance, DefaultInferOptions)
+	want := map[string]any{
+		// The symlink matches *.go and is kept literal, so the glob only
+		// keeps what it shares with the other files: nothing. *_test.go
+		// would match a subset of *.go and is dropped.
+		"*.go":       true,
+		"notes.txt":  true,
+		"link.go":    map[string]any{"symlink": "a.go"},
+		".gitignore": true,
+		"README.md":  true,
+		"CHANGES.md": true,
+		"services/": map[string]any{
+			"*/": map[string]any{"main.go": true},
+		},
+		"mixed/": map[string]any{
+			"a/": map[string]any{"x": true},
+			"b/": map[string]any{"y": true},
+			"c/": map[string]any{"x": true},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("InferGlobs mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+
+	strict := InferGlobs(instance, InferOptions{MinFiles: 4, MinDirs: 4})
+	if _, ok := strict["*_test.go"]; ok {
+		t.Fatalf("expected no *_test.go glob below threshold: %#v", strict)
+	}
+	if _, ok := strict["services/"].(map[string]any)["*/"]; ok {
+		t.Fatalf("expected no */ glob below threshold: %#v", strict)
+	}
+}
+
+func TestInferGlobsDropsCoveredGlobs(t *testing.T) {
+	instance := map[string]any{
+		"test_a.py": true,
+		"test_b.py": true,
+		"test_c.py": true,
+		"main.py":   true,
+		"util.py":   true,
+		"cli.py":    true,
+		"test_a.sh": true,
+		"test_b.sh": true,
+		"test_c.sh": true,
+		"build.sh":  true,
+	}
+
+	got := InferGlobs(instance, DefaultInferOptions)
+	want := map[string]any{
+		// test_*.py matches only files *.py matches too.
+		"*.py": true,
+		// Too few other scripts for *.sh, so the prefix glob stays.
+		"test_*.sh": true,
+		"build.sh":  true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("InferGlobs mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
@@ -10,15 +10,68 @@ type ParseOptions struct {
 	CaseSensitive bool
 }
 
+// VarsKey is the root-level DSL key holding default template variables.
+// It is passed through ParseDSL unparsed.
+const VarsKey = "vars"
+
+// RawSchemaKey marks an entry whose value is a JSON Schema used as-is, for
+// constructs the DSL cannot express. An entry is raw when its value is an
+// object with this key alone.
+const RawSchemaKey = "rawSchema"
+
 func ParseDSL(root any, opts ParseOptions) (map[string]any, error) {
-	switch v := root.(type) {
+	rest, vars := splitVars(root)
+	var out map[string]any
+	var err error
+	switch v := rest.(type) {
 	case map[string]any:
-		return parseNode(v, opts)
+		out, err = parseNode(v, opts)
 	case []any:
-		return parseList("root", v, opts)
+		out, err = parseList("root", v, opts)
 	default:
 		return nil, fmt.Errorf("unsupported DSL root")
 	}
+	if err != nil {
+		return nil, err
+	}
+	if vars != nil {
+		out[VarsKey] = vars
+	}
+	return out, nil
+}
+
+// splitVars removes a root-level vars block (a "vars" key mapping to an
+// object) from the DSL root and returns it separately.
+func splitVars(root any) (any, map[string]any) {
+	switch v := root.(type) {
+	case map[string]any:
+		vars, ok := v[VarsKey].(map[string]any)
+		if !ok {
+			return root, nil
+		}
+		rest := make(map[string]any, len(v)-1)
+		for key, value := range v {
+			if key != VarsKey {
+				rest[key] = value
+			}
+		}
+		return rest, vars
+	case []any:
+		var vars map[string]any
+		rest := make([]any, 0, len(v))
+		for _, item := range v {
+			if entry, ok := item.(map[string]any); ok && len(entry) == 1 {
+				if block, ok := entry[VarsKey].(map[string]any); ok && vars == nil {
+					vars = block
+					continue
+				}
+			}
+			rest = append(rest, item)
+		}
+		return rest, vars
+	default:
+		return root, nil
+	}
 }
 
 func parseNode(node map[string]any, opts ParseOptions) (map[string]any, error) {
@@ -50,6 +103,29 @@ func parseValue(key string, value any, opts ParseOptions) (any, error) {
 			return nil, fmt.Errorf("%s must be string", key)
 		}
 	}
+	// Templates and content sources are resolved into the file's content at
+	// hydrate and validate time; copyFrom annotates a directory and default
+	// names the entry hydrate creates for a glob. Modes are octal strings.
+	if key == "template" || key == "contentFrom" || key == "copyFrom" || key == "verify" || key == "default" || key == "mode" {
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	}
+	if key == RawSchemaKey {
+		if v, ok := value.(map[string]any); ok {
+			return v, nil
+		}
+	}
+	// Severity annotates the enclosing entry. A string value is unambiguous
+	// since entries themselves never map to strings.
+	if key == "severity" {
+		if v, ok := value.(string); ok {
+			if v != "warning" && v != "error" {
+				return nil, fmt.Errorf("severity must be warning or error, got %q", v)
+			}
+			return v, nil
+		}
+	}
 	// Size can be a number or a range object {min, max}
 	if key == "size" {
 		switch v := value.(type) {
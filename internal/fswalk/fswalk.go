@@ -17,8 +17,26 @@ type Options struct {
 	IncludeSize     bool
 	IncludeSHA256   bool
 	IncludeContent  bool
+	IncludeMode     bool
 	MaxContentBytes int64
 	SymlinkPolicy   SymlinkPolicy
+	// MaxDepth limits how many directory levels are read; 0 means no limit.
+	// Directories below the limit are recorded as empty objects.
+	MaxDepth int
+}
+
+// descend returns the options for walking one level deeper, or false when
+// MaxDepth has been reached.
+func (o Options) descend() (Options, bool) {
+	switch o.MaxDepth {
+	case 0:
+		return o, true
+	case 1:
+		return o, false
+	default:
+		o.MaxDepth--
+		return o, true
+	}
 }
 
 type SymlinkPolicy int
@@ -123,11 +141,16 @@ func walkDirInner(dir string, opts Options, schema map[string]any, visited map[s
 		}
 
 		if entry.IsDir() {
+			childOpts, ok := opts.descend()
+			if !ok {
+				out[name+"/"] = map[string]any{}
+				continue
+			}
 			var childSchema map[string]any
 			if cs, ok := schemaExpectsDir(name, schemaProps, schemaPatterns); ok {
 				childSchema = cs
 			}
-			child, derr := walkDirInner(full, opts, childSchema, visited)
+			child, derr := walkDirInner(full, childOpts, childSchema, visited)
 			if derr != nil {
 				return nil, derr
 			}
@@ -163,7 +186,12 @@ func handleSchemaSymlink(name, full string, opts Options, schemaProps, schemaPat
 			// Schema expects dir but target is file — fall through to policy
 			return false, nil
 		}
-		child, err := walkDirInner(full, opts, childSchema, visited)
+		childOpts, ok := opts.descend()
+		if !ok {
+			out[name+"/"] = map[string]any{}
+			return true, nil
+		}
+		child, err := walkDirInner(full, childOpts, childSchema, visited)
 		if err != nil {
 			return false, err
 		}
@@ -218,7 +246,12 @@ func handleFollowSymlink(name, full string, opts Options, visited map[string]boo
 		return false, fmt.Errorf("stat symlink target %s: %w", full, err)
 	}
 	if info.IsDir() {
-		child, derr := walkDirInner(full, opts, nil, visited)
+		childOpts, ok := opts.descend()
+		if !ok {
+			out[name+"/"] = map[string]any{}
+			return true, nil
+		}
+		child, derr := walkDirInner(full, childOpts, nil, visited)
 		if derr != nil {
 			return false, derr
 		}
@@ -322,17 +355,22 @@ func schemaLookupFile(name string, schemaProps, schemaPatterns map[string]any) m
 }
 
 func fileValue(path string, opts Options) (any, error) {
-	if !opts.IncludeSize && !opts.IncludeSHA256 && !opts.IncludeContent {
+	if !opts.IncludeSize && !opts.IncludeSHA256 && !opts.IncludeContent && !opts.IncludeMode {
 		return true, nil
 	}
 
 	attrs := map[string]any{}
-	if opts.IncludeSize {
+	if opts.IncludeSize || opts.IncludeMode {
 		info, err := os.Stat(path)
 		if err != nil {
 			return nil, err
 		}
-		attrs["size"] = info.Size()
+		if opts.IncludeSize {
+			attrs["size"] = info.Size()
+		}
+		if opts.IncludeMode {
+			attrs["mode"] = FormatMode(info.Mode())
+		}
 	}
 
 	if opts.IncludeSHA256 || opts.IncludeContent {
@@ -358,6 +396,12 @@ func fileValue(path string, opts Options) (any, error) {
 	return attrs, nil
 }
 
+// FormatMode renders permission bits the way mode attributes are written:
+// four octal digits, e.g. "0644".
+func FormatMode(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
 func HashFile(path string) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -371,3 +415,41 @@ func HashFile(path string) (string, error) {
 	}
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
+
+// Match says how a directory schema covers an entry.
+type Match int
+
+const (
+	// MatchNone means no property or pattern names the entry.
+	MatchNone Match = iota
+	// MatchLiteral means a property names the entry.
+	MatchLiteral
+	// MatchGlob means a patternProperties pattern matches the entry.
+	MatchGlob
+)
+
+// MatchEntry looks up an instance key (directories end in "/") in a
+// directory schema the way the walker does, and returns the entry's schema
+// and how it was matched.
+func MatchEntry(key string, schema map[string]any) (map[string]any, Match) {
+	props, _ := schema["properties"].(map[string]any)
+	patterns, _ := schema["patternProperties"].(map[string]any)
+	if name, isDir := strings.CutSuffix(key, "/"); isDir {
+		if _, ok := props[key]; ok {
+			childSchema, _ := schemaExpectsDir(name, props, nil)
+			return childSchema, MatchLiteral
+		}
+		if childSchema, ok := schemaExpectsDir(name, nil, patterns); ok {
+			return childSchema, MatchGlob
+		}
+		return nil, MatchNone
+	}
+	if raw, ok := props[key]; ok {
+		fileSchema, _ := raw.(map[string]any)
+		return fileSchema, MatchLiteral
+	}
+	if fileSchema := schemaLookupFile(key, nil, patterns); fileSchema != nil {
+		return fileSchema, MatchGlob
+	}
+	return nil, MatchNone
+}
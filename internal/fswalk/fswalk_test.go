@@ -85,6 +85,31 @@ func TestWalkIncludesSize(t *testing.T) {
 	}
 }
 
+func TestWalkIncludesMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes vary on windows")
+	}
+	root := t.TempDir()
+	writeFile(t, root, "run.sh", "#!/bin/sh\n")
+	if err := os.Chmod(filepath.Join(root, "run.sh"), 0o750); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	got, err := Walk(root, Options{IncludeMode: true})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+
+	want := map[string]any{
+		"run.sh": map[string]any{
+			"mode": "0750",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("instance mismatch: got %#v want %#v", got, want)
+	}
+}
+
 func TestWalkIncludesSHA256(t *testing.T) {
 	root := t.TempDir()
 	contents := []byte("abc")
@@ -529,3 +554,37 @@ func TestWalkSymlinkFollowFile(t *testing.T) {
 		t.Fatalf("expected link.txt to be true (resolved file), got %#v", got["link.txt"])
 	}
 }
+
+func TestWalkMaxDepth(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "a.txt", "hello")
+	mkdirAll(t, filepath.Join(root, "sub", "deep"))
+	writeFile(t, filepath.Join(root, "sub"), "b.txt", "world")
+
+	got, err := Walk(root, Options{MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	want := map[string]any{
+		"a.txt": true,
+		"sub/":  map[string]any{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("depth 1 mismatch: got %#v want %#v", got, want)
+	}
+
+	got, err = Walk(root, Options{MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	want = map[string]any{
+		"a.txt": true,
+		"sub/": map[string]any{
+			"b.txt": true,
+			"deep/": map[string]any{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("depth 2 mismatch: got %#v want %#v", got, want)
+	}
+}
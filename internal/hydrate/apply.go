@@ -1,67 +1,213 @@
 package hydrate
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type ApplyOptions struct {
 	DryRun bool
+	// BackupDir, when set, receives a copy of every entry an update op
+	// overwrites, at the same relative path.
+	BackupDir string
+	// TrashDir, when set, receives removed entries at the same relative path
+	// instead of deleting them.
+	TrashDir string
+	// GitRoot, when set, makes move ops run git mv in that repository
+	// instead of os.Rename. Moves are journaled with it, so rollback and
+	// undo move entries back with git mv as well.
+	GitRoot string
+	// Journal, when set, receives an entry for every path this run creates
+	// or updates, so the run can be undone later.
+	Journal *Journal
+	// Sink, when set, receives the created entries instead of the tree the
+	// plan was built for. Update, remove and move ops are rejected then, and
+	// nothing is journaled or rolled back.
+	Sink Sink
 }
 
+// Apply runs the plan's ops in order. If an op fails, everything the run
+// created or updated so far is reverted in reverse order before the error is
+// returned.
 func Apply(plan Plan, opts ApplyOptions) error {
+	journal := opts.Journal
+	if journal == nil {
+		journal = &Journal{}
+	}
+	start := len(journal.Entries)
+
 	for _, op := range plan.Ops {
-		switch op.Kind {
-		case OpMkdir:
-			if opts.DryRun {
-				continue
-			}
-			if err := os.MkdirAll(op.Path, 0o755); err != nil {
-				return fmt.Errorf("mkdir %s: %w", op.RelPath, err)
-			}
-		case OpWriteFile:
-			if err := applyWrite(op, opts); err != nil {
-				return err
+		if err := applyOp(op, opts, journal); err != nil {
+			run := Journal{Entries: journal.Entries[start:]}
+			if undoErr := Undo(run); undoErr != nil {
+				err = errors.Join(err, fmt.Errorf("rollback: %w", undoErr))
 			}
-		case OpSymlink:
-			if err := applySymlink(op, opts); err != nil {
-				return err
-			}
-		default:
-			return fmt.Errorf("unknown op: %s", op.Kind)
+			journal.Entries = journal.Entries[:start]
+			return err
 		}
 	}
 	return nil
 }
 
-func applyWrite(op Op, opts ApplyOptions) error {
-	if opts.DryRun {
-		return nil
+func applyOp(op Op, opts ApplyOptions, j *Journal) error {
+	sink := opts.Sink
+	if sink == nil {
+		sink = &fileSink{journal: j}
+	} else if op.Kind == OpUpdate || op.Kind == OpRemove || op.Kind == OpMove {
+		return fmt.Errorf("%s %s: only supported in place", op.Kind, op.RelPath)
 	}
-	if err := os.MkdirAll(filepath.Dir(op.Path), 0o755); err != nil {
-		return fmt.Errorf("mkdir for file %s: %w", op.RelPath, err)
+
+	switch op.Kind {
+	case OpMkdir:
+		if opts.DryRun {
+			return nil
+		}
+		if err := sink.Mkdir(op, 0o755); err != nil {
+			return fmt.Errorf("mkdir %s: %w", op.RelPath, err)
+		}
+	case OpWriteFile:
+		return applyWrite(op, opts, sink)
+	case OpSymlink:
+		if opts.DryRun {
+			return nil
+		}
+		if err := sink.Symlink(op, op.Target); err != nil {
+			return fmt.Errorf("symlink %s: %w", op.RelPath, err)
+		}
+	case OpCopy:
+		return applyCopy(op, opts, sink)
+	case OpUpdate:
+		return applyUpdate(op, opts, j)
+	case OpRemove:
+		return applyRemove(op, opts, j)
+	case OpMove:
+		return applyMove(op, opts, j)
+	case OpConflict:
+		// Conflicts are reported, never applied.
+	default:
+		return fmt.Errorf("unknown op: %s", op.Kind)
 	}
+	return nil
+}
 
-	content := []byte{}
+func applyWrite(op Op, opts ApplyOptions, sink Sink) error {
+	if opts.DryRun {
+		return nil
+	}
+	content := ""
 	if op.Content != nil {
-		content = []byte(*op.Content)
+		content = *op.Content
+	}
+	perm := os.FileMode(0o644)
+	if op.Mode != "" {
+		bits, err := strconv.ParseUint(op.Mode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("write %s: invalid mode %q", op.RelPath, op.Mode)
+		}
+		perm = os.FileMode(bits).Perm()
 	}
-	if err := os.WriteFile(op.Path, content, 0o644); err != nil {
+	if err := sink.WriteFile(op, strings.NewReader(content), perm); err != nil {
 		return fmt.Errorf("write %s: %w", op.RelPath, err)
 	}
 	return nil
 }
 
-func applySymlink(op Op, opts ApplyOptions) error {
+// applyCopy copies a single file, directory or symlink from op.Source,
+// preserving its mode. Directory contents are planned as separate ops.
+func applyCopy(op Op, opts ApplyOptions, sink Sink) error {
 	if opts.DryRun {
 		return nil
 	}
-	if err := os.MkdirAll(filepath.Dir(op.Path), 0o755); err != nil {
-		return fmt.Errorf("mkdir for symlink %s: %w", op.RelPath, err)
+	info, err := os.Lstat(op.Source)
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", op.RelPath, err)
 	}
-	if err := os.Symlink(op.Target, op.Path); err != nil {
-		return fmt.Errorf("symlink %s: %w", op.RelPath, err)
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(op.Source)
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", op.RelPath, err)
+		}
+		err = sink.Symlink(op, target)
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", op.RelPath, err)
+		}
+		return nil
+	case info.IsDir():
+		if err := sink.Mkdir(op, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("copy %s: %w", op.RelPath, err)
+		}
+		return nil
 	}
+
+	in, err := os.Open(op.Source)
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", op.RelPath, err)
+	}
+	defer in.Close()
+	if err := sink.WriteFile(op, in, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("copy %s: %w", op.RelPath, err)
+	}
+	return nil
+}
+
+// applyMove renames op.Source to op.Path. It never replaces an existing
+// entry.
+func applyMove(op Op, opts ApplyOptions, j *Journal) error {
+	if opts.DryRun {
+		return nil
+	}
+	if _, err := os.Lstat(op.Path); err == nil {
+		return fmt.Errorf("move %s: destination exists", op.RelPath)
+	}
+	if err := j.mkdirAll(filepath.Dir(op.Path), 0o755); err != nil {
+		return fmt.Errorf("mkdir for move %s: %w", op.RelPath, err)
+	}
+	if err := moveEntry(opts.GitRoot, op.Source, op.Path); err != nil {
+		return fmt.Errorf("move %s: %w", op.RelPath, err)
+	}
+	j.Entries = append(j.Entries, JournalEntry{Action: ActionMove, Path: op.Path, From: op.Source, Git: opts.GitRoot})
 	return nil
 }
+
+// moveEntry renames src to dst, with git mv inside gitRoot when it is set.
+func moveEntry(gitRoot, src, dst string) error {
+	if gitRoot == "" {
+		return os.Rename(src, dst)
+	}
+	cmd := exec.Command("git", "-C", gitRoot, "mv", "--", src, dst)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git mv: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// The umask may have narrowed the mode at create time.
+	return os.Chmod(dst, perm)
+}
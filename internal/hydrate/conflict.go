@@ -0,0 +1,167 @@
+package hydrate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// ConflictReason explains why an existing entry blocks a planned op.
+type ConflictReason string
+
+const (
+	ConflictTypeMismatch  ConflictReason = "type-mismatch"
+	ConflictSymlinkTarget ConflictReason = "symlink-target"
+	ConflictContent       ConflictReason = "content-mismatch"
+	ConflictNotEmpty      ConflictReason = "not-empty"
+	ConflictCollision     ConflictReason = "collision"
+	ConflictCycle         ConflictReason = "cycle"
+)
+
+var conflictLabels = map[ConflictReason]string{
+	ConflictTypeMismatch:  "type mismatch",
+	ConflictSymlinkTarget: "wrong symlink target",
+	ConflictContent:       "content mismatch",
+	ConflictNotEmpty:      "directory not empty",
+	ConflictCollision:     "collision",
+	ConflictCycle:         "move cycle",
+}
+
+// Conflicts returns the conflict ops in the plan.
+func (p Plan) Conflicts() []Op {
+	var out []Op
+	for _, op := range p.Ops {
+		if op.Kind == OpConflict {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// reconcile compares a planned op with whatever already exists at its path.
+// It returns the op when the path is missing, a conflict op when the existing
+// entry is incompatible, and nothing when the entry already matches.
+func reconcile(op Op) []Op {
+	info, err := os.Lstat(op.Path)
+	if err != nil {
+		return []Op{op}
+	}
+	if conflict, ok := conflictFor(op, info); ok {
+		return []Op{conflict}
+	}
+	return nil
+}
+
+func conflictFor(op Op, info os.FileInfo) (Op, bool) {
+	switch op.Kind {
+	case OpMkdir:
+		if found := entryKind(op.Path, info); found != "directory" {
+			return typeConflict(op, "directory", found), true
+		}
+	case OpSymlink:
+		if info.Mode()&os.ModeSymlink == 0 {
+			return typeConflict(op, "symlink", entryKind(op.Path, info)), true
+		}
+		target, err := os.Readlink(op.Path)
+		if err == nil && target != op.Target {
+			return Op{
+				Kind:    OpConflict,
+				Path:    op.Path,
+				RelPath: op.RelPath,
+				Target:  op.Target,
+				Reason:  ConflictSymlinkTarget,
+				Detail:  fmt.Sprintf("points to %s, expected %s", target, op.Target),
+			}, true
+		}
+	case OpWriteFile:
+		if found := entryKind(op.Path, info); found != "file" {
+			return typeConflict(op, "file", found), true
+		}
+		if op.Content != nil {
+			data, err := os.ReadFile(op.Path)
+			if err == nil && string(data) != *op.Content {
+				return Op{
+					Kind:    OpConflict,
+					Path:    op.Path,
+					RelPath: op.RelPath,
+					Content: op.Content,
+					Reason:  ConflictContent,
+				}, true
+			}
+		}
+	case OpCopy:
+		srcInfo, err := os.Lstat(op.Source)
+		if err != nil {
+			return Op{}, false
+		}
+		want := entryKind(op.Source, srcInfo)
+		found := entryKind(op.Path, info)
+		if srcInfo.Mode()&os.ModeSymlink != 0 {
+			want = "symlink"
+			if info.Mode()&os.ModeSymlink != 0 {
+				found = "symlink"
+			}
+		}
+		if found != want {
+			return typeConflict(op, want, found), true
+		}
+		if want == "file" {
+			return copyContentConflict(op)
+		}
+	}
+	return Op{}, false
+}
+
+// copyContentConflict reports an existing file whose content differs from
+// the copy op's source. The conflict carries the source content, so that
+// WithUpdates can overwrite the file like a writefile conflict.
+func copyContentConflict(op Op) (Op, bool) {
+	want, err := os.ReadFile(op.Source)
+	if err != nil {
+		return Op{}, false
+	}
+	have, err := os.ReadFile(op.Path)
+	if err != nil || bytes.Equal(have, want) {
+		return Op{}, false
+	}
+	content := string(want)
+	return Op{
+		Kind:    OpConflict,
+		Path:    op.Path,
+		RelPath: op.RelPath,
+		Content: &content,
+		Source:  op.Source,
+		Reason:  ConflictContent,
+	}, true
+}
+
+func typeConflict(op Op, want, found string) Op {
+	return Op{
+		Kind:    OpConflict,
+		Path:    op.Path,
+		RelPath: op.RelPath,
+		Source:  op.Source,
+		Reason:  ConflictTypeMismatch,
+		Detail:  fmt.Sprintf("expected %s, found %s", want, found),
+	}
+}
+
+// entryKind describes an existing entry, following symlinks the way the
+// filesystem walk does.
+func entryKind(path string, info os.FileInfo) string {
+	if info.Mode()&os.ModeSymlink != 0 {
+		resolved, err := os.Stat(path)
+		if err != nil {
+			return "broken symlink"
+		}
+		info = resolved
+	}
+	switch {
+	case info.IsDir():
+		return "directory"
+	case info.Mode().IsRegular():
+		return "file"
+	default:
+		return "special file"
+	}
+}
@@ -1,11 +1,15 @@
 package hydrate
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
+
+	"dirschema/internal/expand"
 )
 
 func TestBuildPlanBasic(t *testing.T) {
@@ -130,29 +134,13 @@ func TestApplySymlink(t *testing.T) {
 	}
 }
 
-func TestBuildPlanRejectsPatternProperties(t *testing.T) {
+func TestBuildPlanSkipsPatternProperties(t *testing.T) {
 	root := t.TempDir()
 	schema := map[string]any{
 		"type": "object",
 		"patternProperties": map[string]any{
-			"^.*\\.go$": map[string]any{"const": true},
+			"^.*\\.md$": map[string]any{"const": true},
 		},
-		"required": []any{},
-	}
-
-	_, err := BuildPlan(schema, root)
-	if err == nil {
-		t.Fatalf("expected error for patternProperties")
-	}
-	if !contains(err.Error(), "patternProperties") {
-		t.Fatalf("expected patternProperties in error, got: %v", err)
-	}
-}
-
-func TestBuildPlanRejectsNestedPatternProperties(t *testing.T) {
-	root := t.TempDir()
-	schema := map[string]any{
-		"type": "object",
 		"properties": map[string]any{
 			"src/": map[string]any{
 				"type": "object",
@@ -165,31 +153,66 @@ func TestBuildPlanRejectsNestedPatternProperties(t *testing.T) {
 		"required": []any{"src/"},
 	}
 
-	// First create the src directory so we get to the nested patternProperties
-	if err := os.MkdirAll(filepath.Join(root, "src"), 0o755); err != nil {
-		t.Fatalf("mkdir: %v", err)
+	// An existing match satisfies the root pattern.
+	if err := os.WriteFile(filepath.Join(root, "README.md"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
 	}
 
-	_, err := BuildPlan(schema, root)
-	if err == nil {
-		t.Fatalf("expected error for nested patternProperties")
+	plan, err := BuildPlan(schema, root)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := []SkippedPattern{{Dir: "src", Pattern: "^.*\\.go$"}}
+	if !reflect.DeepEqual(plan.Skipped, want) {
+		t.Fatalf("unexpected skipped: %#v", plan.Skipped)
+	}
+	if got := FormatOpsText(plan); got != "mkdir src\nskip pattern *.go in src" {
+		t.Fatalf("unexpected plan text: %q", got)
 	}
-	if !contains(err.Error(), "patternProperties") {
-		t.Fatalf("expected patternProperties in error, got: %v", err)
+	// A pattern that is not a glob is shown as written.
+	raw := Plan{Skipped: []SkippedPattern{{Pattern: "^(a|b)\\.txt$"}}}
+	if got := FormatOpsText(raw); got != "skip pattern ^(a|b)\\.txt$" {
+		t.Fatalf("unexpected plan text: %q", got)
 	}
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsAt(s, substr))
-}
+func TestBuildPlanCreatesGlobDefaults(t *testing.T) {
+	root := t.TempDir()
+	schema := map[string]any{
+		"type": "object",
+		"patternProperties": map[string]any{
+			"^.*\\.go$": map[string]any{
+				"oneOf":               []any{map[string]any{"const": true}, map[string]any{"type": "object"}},
+				expand.DefaultKeyword: "main.go",
+			},
+			"^cmd-.*/$": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"main.go": map[string]any{"type": "object", "properties": map[string]any{"content": map[string]any{"const": "package main\n"}}, "required": []any{"content"}},
+				},
+				"required":            []any{"main.go"},
+				expand.DefaultKeyword: "cmd-app/",
+			},
+			"^.*\\.md$": map[string]any{
+				"oneOf":               []any{map[string]any{"const": true}, map[string]any{"type": "object"}},
+				expand.DefaultKeyword: "README.md",
+			},
+		},
+		"properties": map[string]any{"NOTES.md": map[string]any{}},
+		"required":   []any{"NOTES.md"},
+	}
 
-func containsAt(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+	plan, err := BuildPlan(schema, root)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := "writefile NOTES.md\nmkdir cmd-app\nwritefile cmd-app/main.go (content)\nwritefile main.go"
+	if got := FormatOpsText(plan); got != want {
+		t.Fatalf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+	if len(plan.Skipped) != 0 {
+		t.Fatalf("unexpected skipped: %#v", plan.Skipped)
 	}
-	return false
 }
 
 func TestRequiredKeysTypes(t *testing.T) {
@@ -235,3 +258,565 @@ func TestRequiredKeysTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildPlanSuggestsRename(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "Readme.md"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"README.md": map[string]any{"const": true},
+		},
+		"required": []any{"README.md"},
+	}
+
+	plan, err := BuildPlan(schema, root)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if len(plan.Ops) != 1 {
+		t.Fatalf("expected 1 op, got %v", plan.Ops)
+	}
+	if !reflect.DeepEqual(plan.Ops[0].Suggestions, []string{"Readme.md"}) {
+		t.Fatalf("suggestions = %v", plan.Ops[0].Suggestions)
+	}
+
+	got := FormatOpsText(plan)
+	want := "writefile README.md (or rename Readme.md?)"
+	if got != want {
+		t.Fatalf("FormatOpsText = %q, want %q", got, want)
+	}
+}
+
+func TestBuildPlanCopiesSources(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes vary on windows")
+	}
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "LICENSE"), []byte("MIT\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ci := filepath.Join(src, "ci")
+	if err := os.MkdirAll(filepath.Join(ci, "scripts"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(ci, "scripts", "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	root := t.TempDir()
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"LICENSE": map[string]any{
+				"type":                    "object",
+				"properties":              map[string]any{},
+				"required":                []any{},
+				expand.ContentFromKeyword: filepath.Join(src, "LICENSE"),
+			},
+			"ci/": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"scripts/": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"run.sh": map[string]any{}},
+						"required":   []any{"run.sh"},
+					},
+				},
+				"required":             []any{"scripts/"},
+				expand.CopyFromKeyword: ci,
+			},
+		},
+		"required": []any{"LICENSE", "ci/"},
+	}
+
+	plan, err := BuildPlan(schema, root)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := strings.Join([]string{
+		"copy " + filepath.Join(src, "LICENSE") + " -> LICENSE",
+		"copy " + ci + " -> ci",
+		"copy " + filepath.Join(ci, "scripts") + " -> ci/scripts",
+		"copy " + filepath.Join(ci, "scripts", "run.sh") + " -> ci/scripts/run.sh",
+	}, "\n")
+	if got := FormatOpsText(plan); got != want {
+		t.Fatalf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := Apply(plan, ApplyOptions{}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "LICENSE"))
+	if err != nil || string(data) != "MIT\n" {
+		t.Fatalf("unexpected LICENSE: %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(root, "ci", "scripts", "run.sh"))
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Fatalf("mode not preserved: %v", info.Mode())
+	}
+}
+
+func TestBuildPlanReportsConflicts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink behavior varies on windows")
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "src"), []byte("not a dir"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Symlink("other.txt", filepath.Join(root, "link.txt")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "config.ini"), []byte("old"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "same.txt"), []byte("same"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	fileWithContent := func(content string) map[string]any {
+		return map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"content": map[string]any{"const": content}},
+			"required":   []any{"content"},
+		}
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"src/": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"main.go": map[string]any{}},
+				"required":   []any{"main.go"},
+			},
+			"link.txt": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"symlink": map[string]any{"const": "target.txt"}},
+				"required":   []any{"symlink"},
+			},
+			"config.ini": fileWithContent("new"),
+			"same.txt":   fileWithContent("same"),
+		},
+		"required": []any{"config.ini", "link.txt", "same.txt", "src/"},
+	}
+
+	plan, err := BuildPlan(schema, root)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := strings.Join([]string{
+		"conflict config.ini (content mismatch)",
+		"conflict link.txt (wrong symlink target: points to other.txt, expected target.txt)",
+		"conflict src (type mismatch: expected directory, found file)",
+	}, "\n")
+	if got := FormatOpsText(plan); got != want {
+		t.Fatalf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+	if len(plan.Conflicts()) != 3 {
+		t.Fatalf("expected 3 conflicts, got %d", len(plan.Conflicts()))
+	}
+
+	if err := Apply(plan, ApplyOptions{}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "config.ini"))
+	if err != nil || string(data) != "old" {
+		t.Fatalf("conflicting file was modified: %q, %v", data, err)
+	}
+}
+
+func TestBuildPlanReportsCopyContentConflicts(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "LICENSE"), []byte("MIT\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "LICENSE"), []byte("BSD\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "COPYING"), []byte("MIT\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	copied := map[string]any{
+		"type":                    "object",
+		"properties":              map[string]any{},
+		"required":                []any{},
+		expand.ContentFromKeyword: filepath.Join(src, "LICENSE"),
+	}
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"LICENSE": copied, "COPYING": copied},
+		"required":   []any{"COPYING", "LICENSE"},
+	}
+
+	plan, err := BuildPlan(schema, root)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if got, want := FormatOpsText(plan), "conflict LICENSE (content mismatch)"; got != want {
+		t.Fatalf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := Apply(plan.WithUpdates(), ApplyOptions{}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "LICENSE"))
+	if err != nil || string(data) != "MIT\n" {
+		t.Fatalf("unexpected LICENSE: %q, %v", data, err)
+	}
+}
+
+func TestWithUpdatesOverwritesWithBackup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink behavior varies on windows")
+	}
+
+	root := t.TempDir()
+	backupDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "config.ini"), []byte("a\nold\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Symlink("other.txt", filepath.Join(root, "link.txt")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "src"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"config.ini": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"content": map[string]any{"const": "a\nnew\n"}},
+				"required":   []any{"content"},
+			},
+			"link.txt": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"symlink": map[string]any{"const": "target.txt"}},
+				"required":   []any{"symlink"},
+			},
+			"src/": map[string]any{"type": "object", "properties": map[string]any{}, "required": []any{}},
+		},
+		"required": []any{"config.ini", "link.txt", "src/"},
+	}
+
+	plan, err := BuildPlan(schema, root)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	plan = plan.WithUpdates()
+	want := strings.Join([]string{
+		"update config.ini (content)",
+		"  --- a/config.ini",
+		"  +++ b/config.ini",
+		"  @@ -1,2 +1,2 @@",
+		"   a",
+		"  -old",
+		"  +new",
+		"update link.txt -> target.txt (was other.txt)",
+		"conflict src (type mismatch: expected directory, found file)",
+	}, "\n")
+	if got := FormatOpsText(plan); got != want {
+		t.Fatalf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := Apply(plan, ApplyOptions{DryRun: true}); err != nil {
+		t.Fatalf("Apply dry-run: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "config.ini")); string(data) != "a\nold\n" {
+		t.Fatalf("dry-run modified file: %q", data)
+	}
+
+	if err := Apply(plan, ApplyOptions{BackupDir: backupDir}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "config.ini"))
+	if err != nil || string(data) != "a\nnew\n" {
+		t.Fatalf("unexpected config.ini: %q, %v", data, err)
+	}
+	info, err := os.Stat(filepath.Join(root, "config.ini"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode not preserved: %v, %v", info, err)
+	}
+	if target, err := os.Readlink(filepath.Join(root, "link.txt")); err != nil || target != "target.txt" {
+		t.Fatalf("unexpected link target: %q, %v", target, err)
+	}
+
+	old, err := os.ReadFile(filepath.Join(backupDir, "config.ini"))
+	if err != nil || string(old) != "a\nold\n" {
+		t.Fatalf("unexpected backup: %q, %v", old, err)
+	}
+	if target, err := os.Readlink(filepath.Join(backupDir, "link.txt")); err != nil || target != "other.txt" {
+		t.Fatalf("unexpected backup link: %q, %v", target, err)
+	}
+}
+
+func TestApplyRollsBackOnFailure(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "blocker"), []byte("file"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	content := "x"
+	plan := Plan{Ops: []Op{
+		{Kind: OpMkdir, Path: filepath.Join(root, "a", "b"), RelPath: "a/b"},
+		{Kind: OpWriteFile, Path: filepath.Join(root, "a", "b", "f.txt"), RelPath: "a/b/f.txt", Content: &content},
+		{Kind: OpWriteFile, Path: filepath.Join(root, "blocker", "g.txt"), RelPath: "blocker/g.txt"},
+	}}
+
+	var journal Journal
+	err := Apply(plan, ApplyOptions{Journal: &journal})
+	if err == nil {
+		t.Fatalf("expected apply error")
+	}
+	if _, err := os.Lstat(filepath.Join(root, "a")); !os.IsNotExist(err) {
+		t.Fatalf("expected a/ rolled back, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "blocker")); err != nil {
+		t.Fatalf("pre-existing file removed: %v", err)
+	}
+	if len(journal.Entries) != 0 {
+		t.Fatalf("expected rolled back entries dropped from journal: %#v", journal.Entries)
+	}
+}
+
+func TestUndoRevertsJournal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink behavior varies on windows")
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "config.ini"), []byte("old"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	newContent := "new"
+	plan := Plan{Ops: []Op{
+		{Kind: OpWriteFile, Path: filepath.Join(root, "a", "kept.txt"), RelPath: "a/kept.txt"},
+		{Kind: OpWriteFile, Path: filepath.Join(root, "a", "f.txt"), RelPath: "a/f.txt"},
+		{Kind: OpSymlink, Path: filepath.Join(root, "link"), RelPath: "link", Target: "a/f.txt"},
+		{Kind: OpUpdate, Path: filepath.Join(root, "config.ini"), RelPath: "config.ini", Content: &newContent},
+	}}
+
+	var journal Journal
+	if err := Apply(plan, ApplyOptions{Journal: &journal}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	journalPath := filepath.Join(t.TempDir(), "journal.json")
+	if err := WriteJournal(journalPath, journal); err != nil {
+		t.Fatalf("WriteJournal: %v", err)
+	}
+	loaded, err := LoadJournal(journalPath)
+	if err != nil {
+		t.Fatalf("LoadJournal: %v", err)
+	}
+
+	// A file edited after hydration is left alone, which keeps its
+	// directory in place too.
+	if err := os.WriteFile(filepath.Join(root, "a", "kept.txt"), []byte("edited"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	err = Undo(loaded)
+	if err == nil || !strings.Contains(err.Error(), "changed since hydration") {
+		t.Fatalf("expected changed-file error, got %v", err)
+	}
+	for _, rel := range []string{"a/f.txt", "link"} {
+		if _, err := os.Lstat(filepath.Join(root, rel)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed, got %v", rel, err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a", "kept.txt")); string(data) != "edited" {
+		t.Fatalf("edited file was touched: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "config.ini")); string(data) != "old" {
+		t.Fatalf("expected config.ini restored, got %q", data)
+	}
+}
+
+func TestLoadPlanRebase(t *testing.T) {
+	recorded := t.TempDir()
+	content := "x\n"
+	plan := Plan{Ops: []Op{
+		{Kind: OpMkdir, Path: filepath.Join(recorded, "a"), RelPath: "a"},
+		{Kind: OpWriteFile, Path: filepath.Join(recorded, "a", "f.txt"), RelPath: filepath.Join("a", "f.txt"), Content: &content},
+		{Kind: OpWriteFile, Path: filepath.Join(recorded, "g.txt"), RelPath: "g.txt", Content: &content},
+		{Kind: OpWriteFile, Path: filepath.Join(recorded, "h.txt"), RelPath: "h.txt"},
+	}}
+	payload, err := FormatOpsJSON(plan)
+	if err != nil {
+		t.Fatalf("FormatOpsJSON: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan: %v", err)
+	}
+
+	// In the new root a is a file and g.txt and h.txt already exist.
+	root := t.TempDir()
+	for name, data := range map[string]string{"a": "", "g.txt": content, "h.txt": "other"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	rebased, err := loaded.Rebase(root, nil)
+	if err != nil {
+		t.Fatalf("Rebase: %v", err)
+	}
+	if len(rebased.Ops) != 1 || rebased.Ops[0].Kind != OpConflict || rebased.Ops[0].Path != filepath.Join(root, "a") {
+		t.Fatalf("unexpected rebased plan: %#v", rebased.Ops)
+	}
+}
+
+func TestLoadPlanRejectsUnsafeOps(t *testing.T) {
+	for _, payload := range []string{
+		`{"ops":[{"Kind":"writefile","RelPath":"../escaped.txt"}]}`,
+		`{"ops":[{"Kind":"mkdir","RelPath":"/abs"}]}`,
+		`{"ops":[{"Kind":"copy","RelPath":"a","From":"../b"}]}`,
+		`{"ops":[{"Kind":"remove","RelPath":"../victim","Recursive":true}]}`,
+		`{"ops":[{"Kind":"remove","RelPath":"victim"}]}`,
+		`{"ops":[{"Kind":"move","RelPath":"a","From":"b"}]}`,
+		`{"ops":[{"Kind":"update","RelPath":"a"}]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "plan.json")
+		if err := os.WriteFile(path, []byte(payload), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if _, err := LoadPlan(path); err == nil {
+			t.Fatalf("expected %s to be rejected", payload)
+		}
+	}
+}
+
+func TestRebaseTakesCopySourcesFromSpec(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "tmpl.txt"), []byte("from spec"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	payload := fmt.Sprintf(`{"ops":[{"Kind":"copy","RelPath":"a.txt","Source":%q}]}`, secret)
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(path, []byte(payload), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan: %v", err)
+	}
+
+	root := t.TempDir()
+	if _, err := loaded.Rebase(root, nil); err == nil {
+		t.Fatalf("expected a copy without a spec to be refused")
+	}
+	schema, err := expand.ExpandDSL(map[string]any{
+		"a.txt": map[string]any{"contentFrom": filepath.Join(src, "tmpl.txt")},
+	})
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+	rebased, err := loaded.Rebase(root, schema)
+	if err != nil {
+		t.Fatalf("Rebase: %v", err)
+	}
+	if err := Apply(rebased, ApplyOptions{}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "from spec" {
+		t.Fatalf("expected copy from the spec's source, got %q", data)
+	}
+}
+
+func TestRebaseRefusesOpsBelowSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	outside := t.TempDir()
+	root := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "existing")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	content := "x"
+	for _, plan := range []Plan{
+		{Ops: []Op{{Kind: OpWriteFile, RelPath: filepath.Join("existing", "f.txt"), Content: &content}}},
+		{Ops: []Op{
+			{Kind: OpSymlink, RelPath: "link", Target: outside},
+			{Kind: OpWriteFile, RelPath: filepath.Join("link", "f.txt"), Content: &content},
+		}},
+	} {
+		if _, err := plan.Rebase(root, nil); err == nil {
+			t.Fatalf("expected %#v to be refused", plan.Ops)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Fatalf("wrote outside the root: %v", entries)
+	}
+}
+
+func TestUndoMoveRestoresRemovedSourceDir(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "old", "a.txt")
+	if err := os.MkdirAll(filepath.Dir(src), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(src, []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	plan := Plan{Ops: []Op{{Kind: OpMove, Path: filepath.Join(root, "new", "a.txt"), RelPath: filepath.Join("new", "a.txt"), Source: src}}}
+	var journal Journal
+	if err := Apply(plan, ApplyOptions{Journal: &journal}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	// The move emptied old/, and something cleaned it up since.
+	if err := os.Remove(filepath.Dir(src)); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := Undo(journal); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if data, err := os.ReadFile(src); err != nil || string(data) != "x" {
+		t.Fatalf("expected a.txt moved back: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "new")); err == nil {
+		t.Fatalf("expected the created directory removed")
+	}
+}
+
+func TestApplyFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes vary on windows")
+	}
+	root := t.TempDir()
+	schema, err := expand.ExpandDSL(map[string]any{"run.sh": map[string]any{"mode": "0750"}})
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+	plan, err := BuildPlan(schema, root)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if got := FormatOpsText(plan); got != "writefile run.sh (mode 0750)" {
+		t.Fatalf("unexpected plan: %q", got)
+	}
+	if err := Apply(plan, ApplyOptions{}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(root, "run.sh"))
+	if err != nil || info.Mode().Perm() != 0o750 {
+		t.Fatalf("unexpected mode: %v, %v", info, err)
+	}
+}
@@ -0,0 +1,200 @@
+package hydrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dirschema/internal/fswalk"
+)
+
+// JournalVersion is the journal file format version.
+const JournalVersion = 1
+
+type JournalAction string
+
+const (
+	ActionCreate JournalAction = "create"
+	ActionUpdate JournalAction = "update"
+	ActionRemove JournalAction = "remove"
+	ActionMove   JournalAction = "move"
+)
+
+type EntryKind string
+
+const (
+	EntryDir     EntryKind = "dir"
+	EntryFile    EntryKind = "file"
+	EntrySymlink EntryKind = "symlink"
+)
+
+// Journal records what a hydrate run changed, in order.
+type Journal struct {
+	Version int            `json:"version"`
+	Entries []JournalEntry `json:"entries"`
+}
+
+type JournalEntry struct {
+	Action JournalAction `json:"action"`
+	Kind   EntryKind     `json:"kind"`
+	Path   string        `json:"path"`
+	// Sha256 is the hash of a file as the run left it. Undo leaves files
+	// that changed since then in place.
+	Sha256 string `json:"sha256,omitempty"`
+	// Previous and PreviousTarget hold an updated file's former content and
+	// an updated symlink's former target.
+	Previous       []byte `json:"previous,omitempty"`
+	PreviousTarget string `json:"previousTarget,omitempty"`
+	// Trash is where a removed entry was moved. Entries removed without a
+	// trash directory cannot be restored.
+	Trash string `json:"trash,omitempty"`
+	// From is where a moved entry came from.
+	From string `json:"from,omitempty"`
+	// Git is the repository a move was made in with git mv; undo moves the
+	// entry back with git mv too.
+	Git string `json:"git,omitempty"`
+}
+
+// LoadJournal reads a journal written by WriteJournal.
+func LoadJournal(path string) (Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Journal{}, err
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return Journal{}, fmt.Errorf("parse journal: %w", err)
+	}
+	if j.Version != JournalVersion {
+		return Journal{}, fmt.Errorf("unsupported journal version %d", j.Version)
+	}
+	return j, nil
+}
+
+// WriteJournal writes j as indented JSON.
+func WriteJournal(path string, j Journal) error {
+	j.Version = JournalVersion
+	if j.Entries == nil {
+		j.Entries = []JournalEntry{}
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Undo reverts the journal's entries in reverse order: created paths are
+// removed and updated entries get their previous content or target back.
+// Directories that are no longer empty and files changed since the run are
+// left in place and reported. Undo keeps going past errors and returns them
+// joined.
+func Undo(j Journal) error {
+	var errs []error
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		if err := undoEntry(j.Entries[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", j.Entries[i].Action, j.Entries[i].Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func undoEntry(e JournalEntry) error {
+	if e.Action == ActionMove {
+		if _, err := os.Lstat(e.From); err == nil {
+			return fmt.Errorf("%s exists again; not moving back", e.From)
+		}
+		// The source directory may have been removed once the move emptied
+		// it.
+		if err := os.MkdirAll(filepath.Dir(e.From), 0o755); err != nil {
+			return err
+		}
+		return moveEntry(e.Git, e.Path, e.From)
+	}
+	if e.Action == ActionRemove {
+		if e.Trash == "" {
+			return fmt.Errorf("removed permanently; cannot restore")
+		}
+		if _, err := os.Lstat(e.Path); err == nil {
+			return fmt.Errorf("path exists again; left %s in the trash", e.Trash)
+		}
+		return os.Rename(e.Trash, e.Path)
+	}
+	if _, err := os.Lstat(e.Path); os.IsNotExist(err) {
+		return nil
+	}
+	if e.Kind == EntryFile && e.Sha256 != "" {
+		sum, err := fswalk.HashFile(e.Path)
+		if err != nil {
+			return err
+		}
+		if sum != e.Sha256 {
+			return fmt.Errorf("changed since hydration; left in place")
+		}
+	}
+
+	switch e.Action {
+	case ActionCreate:
+		return os.Remove(e.Path)
+	case ActionUpdate:
+		if e.Kind == EntrySymlink {
+			if err := os.Remove(e.Path); err != nil {
+				return err
+			}
+			return os.Symlink(e.PreviousTarget, e.Path)
+		}
+		info, err := os.Stat(e.Path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(e.Path, e.Previous, info.Mode().Perm())
+	default:
+		return fmt.Errorf("unknown journal action %q", e.Action)
+	}
+}
+
+// mkdirAll is os.MkdirAll that journals every directory it creates.
+func (j *Journal) mkdirAll(path string, perm os.FileMode) error {
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], perm); err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			return err
+		}
+		j.Entries = append(j.Entries, JournalEntry{Action: ActionCreate, Kind: EntryDir, Path: missing[i]})
+	}
+	// Like os.MkdirAll, fail when the path exists but is not a directory.
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: not a directory", path)
+	}
+	return nil
+}
+
+// recordCreated journals a path the run created, if it exists.
+func (j *Journal) recordCreated(path string, kind EntryKind) {
+	if _, err := os.Lstat(path); err != nil {
+		return
+	}
+	entry := JournalEntry{Action: ActionCreate, Kind: kind, Path: path}
+	if kind == EntryFile {
+		entry.Sha256, _ = fswalk.HashFile(path)
+	}
+	j.Entries = append(j.Entries, entry)
+}
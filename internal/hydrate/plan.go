@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+
+	"dirschema/internal/expand"
+	"dirschema/internal/suggest"
 )
 
 type OpKind string
@@ -14,6 +18,18 @@ const (
 	OpMkdir     OpKind = "mkdir"
 	OpWriteFile OpKind = "writefile"
 	OpSymlink   OpKind = "symlink"
+	OpCopy      OpKind = "copy"
+	// OpConflict records an existing entry that blocks the planned op; it is
+	// reported but never applied.
+	OpConflict OpKind = "conflict"
+	// OpUpdate overwrites an existing file's content or symlink target; it is
+	// only planned in update mode.
+	OpUpdate OpKind = "update"
+	// OpRemove deletes an entry the schema does not allow; only prune plans
+	// contain it.
+	OpRemove OpKind = "remove"
+	// OpMove renames an entry; only refactor plans contain it.
+	OpMove OpKind = "move"
 )
 
 type Op struct {
@@ -22,32 +38,79 @@ type Op struct {
 	RelPath string
 	Content *string
 	Target  string
+	// Mode is the octal permission string a writefile op creates the file
+	// with; empty means 0644.
+	Mode string `json:",omitempty"`
+	// Source is the file or directory a copy or move op reads from.
+	Source string `json:",omitempty"`
+	// From is a move op's source relative to the root.
+	From string `json:",omitempty"`
+	// Reason and Detail explain a conflict op.
+	Reason ConflictReason `json:",omitempty"`
+	Detail string         `json:",omitempty"`
+	// Diff shows how an update op changes a file's content.
+	Diff string `json:",omitempty"`
+	// Recursive marks a remove op for a non-empty directory.
+	Recursive bool `json:",omitempty"`
+	// Suggestions lists existing siblings that look like a misspelling of
+	// the missing entry; renaming one of them may be preferable to creating
+	// a new entry.
+	Suggestions []string `json:",omitempty"`
 }
 
 type Plan struct {
 	Ops []Op
+	// Skipped lists glob patterns hydrate left alone because nothing matches
+	// them yet and they declare no default name.
+	Skipped []SkippedPattern
+}
+
+// SkippedPattern is a patternProperties entry that hydrate could not create.
+type SkippedPattern struct {
+	Dir     string `json:"dir"`
+	Pattern string `json:"pattern"`
 }
 
 func BuildPlan(schema map[string]any, root string) (Plan, error) {
-	ops, err := collectOps(schema, root, "")
+	var skipped []SkippedPattern
+	ops, err := collectOps(schema, root, "", &skipped)
 	if err != nil {
 		return Plan{}, err
 	}
 	stableSortOps(ops)
-	return Plan{Ops: ops}, nil
-}
-
-func collectOps(schema map[string]any, root, rel string) ([]Op, error) {
-	// Check for patternProperties - cannot hydrate patterns
-	if _, hasPatterns := schema["patternProperties"]; hasPatterns {
-		if rel == "" {
-			return nil, fmt.Errorf("cannot hydrate schema with patternProperties at root level")
+	sort.Slice(skipped, func(i, j int) bool {
+		if skipped[i].Dir == skipped[j].Dir {
+			return skipped[i].Pattern < skipped[j].Pattern
 		}
-		return nil, fmt.Errorf("cannot hydrate schema with patternProperties at %q", rel)
-	}
+		return skipped[i].Dir < skipped[j].Dir
+	})
+	return Plan{Ops: ops, Skipped: skipped}, nil
+}
 
+func collectOps(schema map[string]any, root, rel string, skipped *[]SkippedPattern) ([]Op, error) {
 	props, _ := schema["properties"].(map[string]any)
 	required := requiredKeys(schema)
+	existing := existingEntries(filepath.Join(root, rel))
+	siblings := unexpectedSiblings(existing, props)
+
+	// Glob entries are only hydrated through their default name, and only
+	// when neither the tree nor the required entries already match them.
+	defaults, err := patternDefaults(schema, rel, append(existing, required...), skipped)
+	if err != nil {
+		return nil, err
+	}
+	if len(defaults) > 0 {
+		merged := make(map[string]any, len(props)+len(defaults))
+		for name, child := range props {
+			merged[name] = child
+		}
+		for name, child := range defaults {
+			merged[name] = child
+			required = append(required, name)
+		}
+		props = merged
+		sort.Strings(required)
+	}
 
 	var ops []Op
 	for _, name := range required {
@@ -61,22 +124,47 @@ func collectOps(schema map[string]any, root, rel string) ([]Op, error) {
 		}
 
 		childRel := filepath.Join(rel, name)
+		if src, ok := childSchema[expand.ContentFromKeyword].(string); ok {
+			op := Op{
+				Kind:        OpCopy,
+				Path:        filepath.Join(root, childRel),
+				RelPath:     childRel,
+				Source:      src,
+				Suggestions: suggest.Candidates(name, siblings),
+			}
+			ops = append(ops, reconcile(op)...)
+			continue
+		}
 		if isDirectorySchema(childSchema, name) {
 			dirRel := strings.TrimSuffix(childRel, string(filepath.Separator)+"")
 			dirRel = strings.TrimSuffix(dirRel, "/")
-			childOps, err := collectOps(childSchema, root, dirRel)
+			dirPath := filepath.Join(root, dirRel)
+			mkdir := Op{
+				Kind:        OpMkdir,
+				Path:        dirPath,
+				RelPath:     dirRel,
+				Suggestions: suggest.Candidates(name, siblings),
+			}
+			dirOps := reconcile(mkdir)
+			if len(dirOps) == 1 && dirOps[0].Kind == OpConflict {
+				// Nothing below a blocked directory can be created.
+				ops = append(ops, dirOps...)
+				continue
+			}
+			childOps, err := collectOps(childSchema, root, dirRel, skipped)
 			if err != nil {
 				return nil, err
 			}
-			dirPath := filepath.Join(root, dirRel)
-			if !pathExists(dirPath) {
-				op := Op{
-					Kind:    OpMkdir,
-					Path:    dirPath,
-					RelPath: dirRel,
+			if src, ok := childSchema[expand.CopyFromKeyword].(string); ok {
+				copyOps, err := copyTreeOps(src, root, dirRel)
+				if err != nil {
+					return nil, err
 				}
-				ops = append(ops, op)
+				ops = append(ops, copyOps...)
+				ops = append(ops, uncoveredOps(childOps, copyOps)...)
+				continue
 			}
+			ops = append(ops, dirOps...)
 			ops = append(ops, childOps...)
 			continue
 		}
@@ -85,32 +173,166 @@ func collectOps(schema map[string]any, root, rel string) ([]Op, error) {
 			return nil, err
 		} else if ok {
 			op := Op{
-				Kind:    OpSymlink,
-				Path:    filepath.Join(root, childRel),
-				RelPath: childRel,
-				Target:  target,
-			}
-			if !pathExists(op.Path) {
-				ops = append(ops, op)
+				Kind:        OpSymlink,
+				Path:        filepath.Join(root, childRel),
+				RelPath:     childRel,
+				Target:      target,
+				Suggestions: suggest.Candidates(name, siblings),
 			}
+			ops = append(ops, reconcile(op)...)
 			continue
 		}
 
 		content := contentFromSchema(childSchema)
 		op := Op{
-			Kind:    OpWriteFile,
-			Path:    filepath.Join(root, childRel),
-			RelPath: childRel,
-			Content: content,
-		}
-		if !pathExists(op.Path) {
-			ops = append(ops, op)
+			Kind:        OpWriteFile,
+			Path:        filepath.Join(root, childRel),
+			RelPath:     childRel,
+			Content:     content,
+			Mode:        constFromSchema(childSchema, "mode"),
+			Suggestions: suggest.Candidates(name, siblings),
 		}
+		ops = append(ops, reconcile(op)...)
 	}
 
 	return ops, nil
 }
 
+// copyTreeOps plans a copy op for the directory itself and for every entry
+// under src that does not exist yet below root/rel.
+func copyTreeOps(src, root, rel string) ([]Op, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, fmt.Errorf("copyFrom for %q: %w", rel, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("copyFrom for %q: %s is not a directory", rel, src)
+	}
+
+	var ops []Op
+	err = filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		srcRel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dstRel := rel
+		if srcRel != "." {
+			dstRel = filepath.Join(rel, srcRel)
+		}
+		op := Op{Kind: OpCopy, Path: filepath.Join(root, dstRel), RelPath: dstRel, Source: path}
+		planned := reconcile(op)
+		ops = append(ops, planned...)
+		if d.IsDir() && len(planned) == 1 && planned[0].Kind == OpConflict {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("copyFrom for %q: %w", rel, err)
+	}
+	return ops, nil
+}
+
+// uncoveredOps drops ops for paths that a copy op already creates.
+func uncoveredOps(ops, copies []Op) []Op {
+	covered := make(map[string]bool, len(copies))
+	for _, c := range copies {
+		covered[c.RelPath] = true
+	}
+	var out []Op
+	for _, op := range ops {
+		if !covered[op.RelPath] {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// patternDefaults returns the schemas of glob entries to create under their
+// declared default names, keyed by that name. Patterns that match none of
+// names and have no default are recorded as skipped.
+func patternDefaults(schema map[string]any, rel string, names []string, skipped *[]SkippedPattern) (map[string]any, error) {
+	patterns, _ := schema["patternProperties"].(map[string]any)
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(patterns))
+	for pattern := range patterns {
+		keys = append(keys, pattern)
+	}
+	sort.Strings(keys)
+
+	defaults := map[string]any{}
+	for _, pattern := range keys {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if matchesAny(re, names) {
+			continue
+		}
+		childSchema, ok := patterns[pattern].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("schema for pattern %q must be object", pattern)
+		}
+		name, _ := childSchema[expand.DefaultKeyword].(string)
+		if name == "" {
+			*skipped = append(*skipped, SkippedPattern{Dir: rel, Pattern: pattern})
+			continue
+		}
+		if !re.MatchString(name) {
+			return nil, fmt.Errorf("default %q does not match pattern %q", name, pattern)
+		}
+		if _, taken := defaults[name]; !taken {
+			defaults[name] = childSchema
+		}
+	}
+	return defaults, nil
+}
+
+func matchesAny(re *regexp.Regexp, names []string) bool {
+	for _, name := range names {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// existingEntries lists the entries in dir. Directory names get a trailing
+// "/" to match DSL keys.
+func existingEntries(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	out := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// unexpectedSiblings lists the existing entries that the schema does not
+// name as literal properties.
+func unexpectedSiblings(existing []string, props map[string]any) []string {
+	var out []string
+	for _, name := range existing {
+		if _, ok := props[name]; ok {
+			continue
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
 func requiredKeys(schema map[string]any) []string {
 	var out []string
 	if raw, ok := schema["required"]; ok {
@@ -179,6 +401,15 @@ func contentFromSchema(schema map[string]any) *string {
 	return nil
 }
 
+// constFromSchema returns the string const of a file descriptor property,
+// or "" when it is not pinned.
+func constFromSchema(schema map[string]any, name string) string {
+	props, _ := schema["properties"].(map[string]any)
+	prop, _ := props[name].(map[string]any)
+	val, _ := prop["const"].(string)
+	return val
+}
+
 func stableSortOps(ops []Op) {
 	sort.Slice(ops, func(i, j int) bool {
 		if ops[i].RelPath == ops[j].RelPath {
@@ -188,11 +419,6 @@ func stableSortOps(ops []Op) {
 	})
 }
 
-func pathExists(path string) bool {
-	_, err := os.Stat(path)
-	return err == nil
-}
-
 func symlinkTargetFromSchema(schema map[string]any) (string, bool, error) {
 	props, ok := schema["properties"].(map[string]any)
 	if !ok {
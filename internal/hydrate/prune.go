@@ -0,0 +1,215 @@
+package hydrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// PruneOptions controls which removals BuildPrunePlan may plan.
+type PruneOptions struct {
+	// Recursive allows removing non-empty directories. Without it they are
+	// reported as conflicts.
+	Recursive bool
+}
+
+// BuildPrunePlan plans the removal of every entry in instance that the
+// schema does not allow: names rejected by additionalProperties: false and
+// names whose schema is false. Entries whose parent resolves outside root
+// (through a followed symlink) are refused.
+func BuildPrunePlan(schema, instance map[string]any, root string, opts PruneOptions) (Plan, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return Plan{}, fmt.Errorf("resolve root: %w", err)
+	}
+	var ops []Op
+	if err := collectRemovals(schema, instance, root, realRoot, "", opts, &ops); err != nil {
+		return Plan{}, err
+	}
+	stableSortOps(ops)
+	return Plan{Ops: ops}, nil
+}
+
+func collectRemovals(schema, instance map[string]any, root, realRoot, rel string, opts PruneOptions, ops *[]Op) error {
+	names := make([]string, 0, len(instance))
+	for name := range instance {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		children, allowed, err := entrySchemas(schema, name)
+		if err != nil {
+			return err
+		}
+		childRel := filepath.Join(rel, strings.TrimSuffix(name, "/"))
+		if !allowed {
+			op, err := removeOp(root, realRoot, childRel, strings.HasSuffix(name, "/"), opts)
+			if err != nil {
+				return err
+			}
+			*ops = append(*ops, op)
+			continue
+		}
+		childInstance, ok := instance[name].(map[string]any)
+		if !ok || !strings.HasSuffix(name, "/") {
+			continue
+		}
+		for _, child := range children {
+			if err := collectRemovals(child, childInstance, root, realRoot, childRel, opts, ops); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// entrySchemas returns the object schemas that apply to name within a
+// directory schema, and whether the name is allowed there at all.
+func entrySchemas(schema map[string]any, name string) ([]map[string]any, bool, error) {
+	var applicable []any
+	props, _ := schema["properties"].(map[string]any)
+	if child, ok := props[name]; ok {
+		applicable = append(applicable, child)
+	}
+	patterns, _ := schema["patternProperties"].(map[string]any)
+	for pattern, child := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if re.MatchString(name) {
+			applicable = append(applicable, child)
+		}
+	}
+	if len(applicable) == 0 {
+		if additional, ok := schema["additionalProperties"]; ok {
+			applicable = append(applicable, additional)
+		}
+	}
+
+	var out []map[string]any
+	for _, child := range applicable {
+		switch v := child.(type) {
+		case bool:
+			if !v {
+				return nil, false, nil
+			}
+		case map[string]any:
+			out = append(out, v)
+		}
+	}
+	return out, true, nil
+}
+
+func removeOp(root, realRoot, rel string, isDir bool, opts PruneOptions) (Op, error) {
+	path := filepath.Join(root, rel)
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return Op{}, fmt.Errorf("resolve %s: %w", rel, err)
+	}
+	if parent != realRoot && !strings.HasPrefix(parent, realRoot+string(filepath.Separator)) {
+		return Op{}, fmt.Errorf("refusing to remove %s: it resolves outside the root", rel)
+	}
+
+	op := Op{Kind: OpRemove, Path: path, RelPath: rel}
+	if !isDir {
+		return op, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return Op{}, fmt.Errorf("read %s: %w", rel, err)
+	}
+	if len(entries) > 0 {
+		if !opts.Recursive {
+			return Op{
+				Kind:    OpConflict,
+				Path:    path,
+				RelPath: rel,
+				Reason:  ConflictNotEmpty,
+				Detail:  "use --recursive to remove it",
+			}, nil
+		}
+		op.Recursive = true
+	}
+	return op, nil
+}
+
+func applyRemove(op Op, opts ApplyOptions, j *Journal) error {
+	if opts.DryRun {
+		return nil
+	}
+	if opts.TrashDir != "" {
+		dst := filepath.Join(opts.TrashDir, op.RelPath)
+		if err := moveToTrash(op.Path, dst); err != nil {
+			return fmt.Errorf("trash %s: %w", op.RelPath, err)
+		}
+		j.Entries = append(j.Entries, JournalEntry{Action: ActionRemove, Path: op.Path, Trash: dst})
+		return nil
+	}
+
+	remove := os.Remove
+	if op.Recursive {
+		remove = os.RemoveAll
+	}
+	if err := remove(op.Path); err != nil {
+		return fmt.Errorf("remove %s: %w", op.RelPath, err)
+	}
+	j.Entries = append(j.Entries, JournalEntry{Action: ActionRemove, Path: op.Path})
+	return nil
+}
+
+// moveToTrash renames src to dst, copying across filesystems when a rename
+// is not possible.
+func moveToTrash(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyTree(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies src to dst, preserving modes and symlinks.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.Mkdir(target, info.Mode().Perm())
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
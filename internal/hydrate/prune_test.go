@@ -0,0 +1,128 @@
+package hydrate
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func pruneSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"keep.txt": map[string]any{"const": true},
+			"secret":   false,
+			"src/": map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"main.go": map[string]any{"const": true}},
+				"additionalProperties": false,
+				"required":             []any{"main.go"},
+			},
+		},
+		"patternProperties": map[string]any{
+			"^.*\\.tmp$": false,
+			"^.*\\.md$":  map[string]any{"const": true},
+		},
+		"additionalProperties": false,
+		"required":             []any{"keep.txt"},
+	}
+}
+
+func TestBuildPrunePlan(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"keep.txt", "README.md", "secret", "x.tmp", "extra", "src/main.go", "src/old.go", "build/out.o"} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	instance := map[string]any{
+		"keep.txt":  true,
+		"README.md": true,
+		"secret":    true,
+		"x.tmp":     true,
+		"extra":     true,
+		"src/":      map[string]any{"main.go": true, "old.go": true},
+		"build/":    map[string]any{"out.o": true},
+	}
+
+	plan, err := BuildPrunePlan(pruneSchema(), instance, root, PruneOptions{})
+	if err != nil {
+		t.Fatalf("BuildPrunePlan: %v", err)
+	}
+	want := strings.Join([]string{
+		"conflict build (directory not empty: use --recursive to remove it)",
+		"remove extra",
+		"remove secret",
+		"remove src/old.go",
+		"remove x.tmp",
+	}, "\n")
+	if got := FormatOpsText(plan); got != want {
+		t.Fatalf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	plan, err = BuildPrunePlan(pruneSchema(), instance, root, PruneOptions{Recursive: true})
+	if err != nil {
+		t.Fatalf("BuildPrunePlan: %v", err)
+	}
+	trash := filepath.Join(t.TempDir(), "trash")
+	var journal Journal
+	if err := Apply(plan, ApplyOptions{TrashDir: trash, Journal: &journal}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	for _, rel := range []string{"build", "extra", "secret", "src/old.go", "x.tmp"} {
+		if _, err := os.Lstat(filepath.Join(root, rel)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed, got %v", rel, err)
+		}
+		if _, err := os.Lstat(filepath.Join(trash, rel)); err != nil {
+			t.Fatalf("expected %s in trash: %v", rel, err)
+		}
+	}
+	for _, rel := range []string{"keep.txt", "README.md", "src/main.go"} {
+		if _, err := os.Stat(filepath.Join(root, rel)); err != nil {
+			t.Fatalf("expected %s kept: %v", rel, err)
+		}
+	}
+
+	if err := Undo(journal); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "build", "out.o")); err != nil {
+		t.Fatalf("expected trashed dir restored: %v", err)
+	}
+}
+
+func TestBuildPrunePlanRefusesPathsOutsideRoot(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlink behavior varies on windows")
+	}
+
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "data"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	root := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "linked")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"linked/": map[string]any{"type": "object", "properties": map[string]any{}, "additionalProperties": false},
+		},
+	}
+	instance := map[string]any{"linked/": map[string]any{"data": true}}
+
+	_, err := BuildPrunePlan(schema, instance, root, PruneOptions{})
+	if err == nil || !strings.Contains(err.Error(), "outside the root") {
+		t.Fatalf("expected outside-root error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "data")); err != nil {
+		t.Fatalf("outside file touched: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package hydrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dirschema/internal/expand"
+)
+
+// LoadPlan reads a plan written by FormatOpsJSON, e.g. a recorded
+// interactive session. Only ops that create entries can be replayed, and
+// every path must stay below the root; recorded conflicts are dropped, as
+// Rebase finds the conflicts of the tree the plan is replayed into.
+func LoadPlan(path string) (Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Plan{}, err
+	}
+	var payload PlanReport
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return Plan{}, fmt.Errorf("parse plan %s: %w", path, err)
+	}
+	plan := Plan{Skipped: payload.Skipped}
+	for _, op := range payload.Ops {
+		switch op.Kind {
+		case OpMkdir, OpWriteFile, OpSymlink, OpCopy:
+		case OpConflict:
+			continue
+		default:
+			return Plan{}, fmt.Errorf("parse plan %s: op %s %s cannot be replayed", path, op.Kind, op.RelPath)
+		}
+		if !filepath.IsLocal(op.RelPath) {
+			return Plan{}, fmt.Errorf("parse plan %s: op %s has invalid RelPath %q", path, op.Kind, op.RelPath)
+		}
+		if op.From != "" && !filepath.IsLocal(op.From) {
+			return Plan{}, fmt.Errorf("parse plan %s: op %s has invalid From %q", path, op.Kind, op.From)
+		}
+		// Copy sources are found again in the spec by Rebase.
+		op.Path, op.Source = "", ""
+		plan.Ops = append(plan.Ops, op)
+	}
+	return plan, nil
+}
+
+// Rebase points a recorded plan's ops at root and reconciles the ops that
+// create entries with what root already holds, so the plan can be replayed
+// into another tree or after the original one changed. Entries below a
+// directory that is now blocked are dropped. Copy sources are taken from
+// the contentFrom and copyFrom annotations of schema, never from the
+// recording; a plan that copies needs the spec it was recorded from. Ops
+// below a symlink, recorded or already in root, are refused so that the
+// plan cannot write outside root.
+func (p Plan) Rebase(root string, schema map[string]any) (Plan, error) {
+	out := Plan{Skipped: p.Skipped}
+	var blocked, links []string
+	for _, op := range p.Ops {
+		if underAny(op.RelPath, blocked) {
+			continue
+		}
+		if underAny(op.RelPath, links) {
+			return Plan{}, fmt.Errorf("%s %s: below symlink in plan", op.Kind, op.RelPath)
+		}
+		if err := checkParents(root, op.RelPath); err != nil {
+			return Plan{}, fmt.Errorf("%s %s: %w", op.Kind, op.RelPath, err)
+		}
+		op.Path = filepath.Join(root, op.RelPath)
+		switch op.Kind {
+		case OpSymlink:
+			links = append(links, op.RelPath)
+		case OpCopy:
+			src, ok := copySource(schema, op.RelPath)
+			if !ok {
+				return Plan{}, fmt.Errorf("copy %s: no contentFrom or copyFrom in the spec", op.RelPath)
+			}
+			op.Source = src
+		}
+		planned := reconcile(op)
+		if len(planned) == 1 && planned[0].Kind == OpConflict {
+			blocked = append(blocked, op.RelPath)
+		}
+		out.Ops = append(out.Ops, planned...)
+	}
+	return out, nil
+}
+
+// checkParents refuses a path whose parent directories in root include a
+// symlink or a non-directory.
+func checkParents(root, rel string) error {
+	dir := root
+	parts := strings.Split(rel, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", part)
+		}
+		if !info.IsDir() {
+			// reconcile reports the parent as a conflict.
+			return nil
+		}
+	}
+	return nil
+}
+
+// copySource finds the source a copy op at rel reads from: the contentFrom
+// of the entry itself, or the matching path below the copyFrom of a
+// directory that contains it.
+func copySource(schema map[string]any, rel string) (string, bool) {
+	parts := strings.Split(rel, string(filepath.Separator))
+	node := schema
+	for i, name := range parts {
+		child, ok := entrySchema(node, name)
+		if !ok {
+			return "", false
+		}
+		if src, ok := child[expand.ContentFromKeyword].(string); ok && i == len(parts)-1 {
+			return src, true
+		}
+		if src, ok := child[expand.CopyFromKeyword].(string); ok {
+			return filepath.Join(append([]string{src}, parts[i+1:]...)...), true
+		}
+		node = child
+	}
+	return "", false
+}
+
+// entrySchema looks up name, as a directory or a file, in a directory
+// schema's properties and then its patternProperties.
+func entrySchema(schema map[string]any, name string) (map[string]any, bool) {
+	props, _ := schema["properties"].(map[string]any)
+	for _, key := range []string{name + "/", name} {
+		if child, ok := props[key].(map[string]any); ok {
+			return child, true
+		}
+	}
+	patterns, _ := schema["patternProperties"].(map[string]any)
+	keys := make([]string, 0, len(patterns))
+	for pattern := range patterns {
+		keys = append(keys, pattern)
+	}
+	sort.Strings(keys)
+	for _, pattern := range keys {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(name+"/") || re.MatchString(name) {
+			if child, ok := patterns[pattern].(map[string]any); ok {
+				return child, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func underAny(rel string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(rel, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
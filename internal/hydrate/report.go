@@ -1,13 +1,19 @@
 package hydrate
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+
+	"dirschema/internal/expand"
+)
 
 type PlanReport struct {
-	Ops []Op `json:"ops"`
+	Ops     []Op             `json:"ops"`
+	Skipped []SkippedPattern `json:"skipped,omitempty"`
 }
 
 func FormatOpsJSON(plan Plan) ([]byte, error) {
-	payload := PlanReport{Ops: plan.Ops}
+	payload := PlanReport{Ops: plan.Ops, Skipped: plan.Skipped}
 	return json.Marshal(payload)
 }
 
@@ -15,17 +21,68 @@ func FormatOpsText(plan Plan) string {
 	out := ""
 	for i, op := range plan.Ops {
 		line := string(op.Kind) + " " + op.RelPath
+		if op.Kind == OpCopy {
+			line = string(op.Kind) + " " + op.Source + " -> " + op.RelPath
+		}
+		if op.Kind == OpMove {
+			line = string(op.Kind) + " " + op.From + " -> " + op.RelPath
+		}
+		if op.Kind == OpConflict {
+			reason := conflictLabels[op.Reason]
+			if op.Detail != "" {
+				reason += ": " + op.Detail
+			}
+			line += " (" + reason + ")"
+		}
+		if op.Kind == OpRemove && op.Recursive {
+			line += " (recursive)"
+		}
 		if op.Kind == OpWriteFile && op.Content != nil {
 			line += " (content)"
 		}
+		if op.Kind == OpWriteFile && op.Mode != "" {
+			line += " (mode " + op.Mode + ")"
+		}
 		if op.Kind == OpSymlink {
 			line += " -> " + op.Target
 		}
+		if op.Kind == OpUpdate {
+			if op.Content != nil {
+				line += " (content)"
+			} else {
+				line += " -> " + op.Target
+				if op.Detail != "" {
+					line += " (" + op.Detail + ")"
+				}
+			}
+			if op.Diff != "" {
+				line += "\n  " + strings.ReplaceAll(op.Diff, "\n", "\n  ")
+			}
+		}
+		if len(op.Suggestions) > 0 {
+			line += " (or rename " + strings.TrimSuffix(op.Suggestions[0], "/") + "?)"
+		}
 		if i == 0 {
 			out = line
 		} else {
 			out += "\n" + line
 		}
 	}
+	for _, skip := range plan.Skipped {
+		// Show the glob the spec was written with when the regex converts.
+		pattern := skip.Pattern
+		if glob, ok := expand.RegexToGlob(pattern); ok {
+			pattern = glob
+		}
+		line := "skip pattern " + pattern
+		if skip.Dir != "" {
+			line += " in " + skip.Dir
+		}
+		if out == "" {
+			out = line
+		} else {
+			out += "\n" + line
+		}
+	}
 	return out
 }
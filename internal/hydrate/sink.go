@@ -0,0 +1,176 @@
+package hydrate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// Sink receives the entries Apply creates. Each method creates the missing
+// parent directories of the op's entry first and gives the entry exactly perm.
+type Sink interface {
+	Mkdir(op Op, perm os.FileMode) error
+	WriteFile(op Op, content io.Reader, perm os.FileMode) error
+	Symlink(op Op, target string) error
+}
+
+// fileSink writes entries to disk. With an empty dir it writes to op.Path and
+// journals what it creates; otherwise it writes op.RelPath below dir.
+type fileSink struct {
+	dir     string
+	journal *Journal
+}
+
+// NewDirSink returns a sink that materializes a plan's entries below dir
+// instead of the tree the plan was built for. Apply does not roll back what
+// it wrote there.
+func NewDirSink(dir string) Sink {
+	return &fileSink{dir: dir, journal: &Journal{}}
+}
+
+func (s *fileSink) path(op Op) string {
+	if s.dir == "" {
+		return op.Path
+	}
+	return filepath.Join(s.dir, op.RelPath)
+}
+
+func (s *fileSink) Mkdir(op Op, perm os.FileMode) error {
+	dst := s.path(op)
+	if err := s.journal.mkdirAll(dst, perm); err != nil {
+		return err
+	}
+	return os.Chmod(dst, perm)
+}
+
+func (s *fileSink) WriteFile(op Op, content io.Reader, perm os.FileMode) error {
+	dst := s.path(op)
+	if err := s.journal.mkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, content)
+	err = errors.Join(err, out.Close())
+	s.journal.recordCreated(dst, EntryFile)
+	if err != nil {
+		return err
+	}
+	// The umask may have narrowed the mode at create time.
+	return os.Chmod(dst, perm)
+}
+
+func (s *fileSink) Symlink(op Op, target string) error {
+	dst := s.path(op)
+	if err := s.journal.mkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return err
+	}
+	s.journal.recordCreated(dst, EntrySymlink)
+	return nil
+}
+
+// ArchiveSink writes a plan's entries to a gzip-compressed tar stream, named
+// by their paths relative to the root. Close must be called to flush it.
+type ArchiveSink struct {
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	dirs    map[string]bool
+	modTime time.Time
+}
+
+func NewArchiveSink(w io.Writer) *ArchiveSink {
+	gz := gzip.NewWriter(w)
+	return &ArchiveSink{
+		gz:      gz,
+		tw:      tar.NewWriter(gz),
+		dirs:    map[string]bool{},
+		modTime: time.Now().Truncate(time.Second),
+	}
+}
+
+func (s *ArchiveSink) Mkdir(op Op, perm os.FileMode) error {
+	name := filepath.ToSlash(op.RelPath)
+	if err := s.parents(name); err != nil {
+		return err
+	}
+	return s.dir(name, perm)
+}
+
+func (s *ArchiveSink) WriteFile(op Op, content io.Reader, perm os.FileMode) error {
+	name := filepath.ToSlash(op.RelPath)
+	if err := s.parents(name); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(perm.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  s.modTime,
+	}
+	if err := s.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = s.tw.Write(data)
+	return err
+}
+
+func (s *ArchiveSink) Symlink(op Op, target string) error {
+	name := filepath.ToSlash(op.RelPath)
+	if err := s.parents(name); err != nil {
+		return err
+	}
+	return s.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     name,
+		Linkname: target,
+		Mode:     0o777,
+		ModTime:  s.modTime,
+	})
+}
+
+// Close finishes the tar stream and the gzip stream. It does not close the
+// underlying writer.
+func (s *ArchiveSink) Close() error {
+	return errors.Join(s.tw.Close(), s.gz.Close())
+}
+
+// parents writes headers for the directories above name that the archive
+// does not contain yet.
+func (s *ArchiveSink) parents(name string) error {
+	parent := path.Dir(name)
+	if parent == "." || s.dirs[parent] {
+		return nil
+	}
+	if err := s.parents(parent); err != nil {
+		return err
+	}
+	return s.dir(parent, 0o755)
+}
+
+func (s *ArchiveSink) dir(name string, perm os.FileMode) error {
+	if s.dirs[name] {
+		return nil
+	}
+	s.dirs[name] = true
+	return s.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     int64(perm.Perm()),
+		ModTime:  s.modTime,
+	})
+}
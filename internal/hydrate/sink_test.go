@@ -0,0 +1,108 @@
+package hydrate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestApplyToSinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes vary on windows")
+	}
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "run.sh"), []byte("#!/bin/sh\n"), 0o750); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	root := t.TempDir()
+	content := "hello\n"
+	plan := Plan{Ops: []Op{
+		{Kind: OpMkdir, Path: filepath.Join(root, "a"), RelPath: "a"},
+		{Kind: OpWriteFile, Path: filepath.Join(root, "a", "b", "f.txt"), RelPath: "a/b/f.txt", Content: &content},
+		{Kind: OpCopy, Path: filepath.Join(root, "bin", "run.sh"), RelPath: "bin/run.sh", Source: filepath.Join(src, "run.sh")},
+		{Kind: OpSymlink, Path: filepath.Join(root, "link"), RelPath: "link", Target: "a/b/f.txt"},
+		{Kind: OpConflict, Path: filepath.Join(root, "c"), RelPath: "c", Reason: ConflictTypeMismatch},
+	}}
+
+	t.Run("dir", func(t *testing.T) {
+		out := t.TempDir()
+		if err := Apply(plan, ApplyOptions{Sink: NewDirSink(out)}); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(out, "a", "b", "f.txt"))
+		if err != nil || string(data) != content {
+			t.Fatalf("unexpected f.txt: %q, %v", data, err)
+		}
+		info, err := os.Stat(filepath.Join(out, "bin", "run.sh"))
+		if err != nil || info.Mode().Perm() != 0o750 {
+			t.Fatalf("mode not preserved: %v, %v", info, err)
+		}
+		if target, err := os.Readlink(filepath.Join(out, "link")); err != nil || target != "a/b/f.txt" {
+			t.Fatalf("unexpected link target: %q, %v", target, err)
+		}
+		entries, err := os.ReadDir(root)
+		if err != nil || len(entries) != 0 {
+			t.Fatalf("expected plan root untouched, got %d entries (%v)", len(entries), err)
+		}
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		var buf bytes.Buffer
+		sink := NewArchiveSink(&buf)
+		if err := Apply(plan, ApplyOptions{Sink: sink}); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+
+		gz, err := gzip.NewReader(&buf)
+		if err != nil {
+			t.Fatalf("gzip: %v", err)
+		}
+		tr := tar.NewReader(gz)
+		var got []string
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("tar: %v", err)
+			}
+			entry := fmt.Sprintf("%s %o", hdr.Name, hdr.Mode)
+			if hdr.Typeflag == tar.TypeSymlink {
+				entry += " -> " + hdr.Linkname
+			}
+			got = append(got, entry)
+		}
+		want := []string{
+			"a/ 755",
+			"a/b/ 755",
+			"a/b/f.txt 644",
+			"bin/ 755",
+			"bin/run.sh 750",
+			"link 777 -> a/b/f.txt",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("unexpected archive entries:\n%s", strings.Join(got, "\n"))
+		}
+	})
+}
+
+func TestApplyToSinkRejectsInPlaceOps(t *testing.T) {
+	root := t.TempDir()
+	plan := Plan{Ops: []Op{{Kind: OpRemove, Path: filepath.Join(root, "x"), RelPath: "x"}}}
+	err := Apply(plan, ApplyOptions{Sink: NewDirSink(t.TempDir())})
+	if err == nil || !strings.Contains(err.Error(), "only supported in place") {
+		t.Fatalf("expected in-place error, got %v", err)
+	}
+}
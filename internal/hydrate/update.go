@@ -0,0 +1,151 @@
+package hydrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dirschema/internal/fswalk"
+	"dirschema/internal/textdiff"
+)
+
+// maxUpdateDiffLines bounds the diff attached to an update op.
+const maxUpdateDiffLines = 200
+
+// WithUpdates turns content and symlink-target conflicts into update ops
+// that overwrite the existing entry. Type mismatches remain conflicts.
+func (p Plan) WithUpdates() Plan {
+	ops := make([]Op, len(p.Ops))
+	for i, op := range p.Ops {
+		ops[i] = op
+		if op.Kind != OpConflict {
+			continue
+		}
+		switch op.Reason {
+		case ConflictContent:
+			ops[i] = Op{
+				Kind:    OpUpdate,
+				Path:    op.Path,
+				RelPath: op.RelPath,
+				Content: op.Content,
+				Diff:    contentDiff(op),
+			}
+		case ConflictSymlinkTarget:
+			update := Op{
+				Kind:    OpUpdate,
+				Path:    op.Path,
+				RelPath: op.RelPath,
+				Target:  op.Target,
+			}
+			if current, err := os.Readlink(op.Path); err == nil {
+				update.Detail = "was " + current
+			}
+			ops[i] = update
+		}
+	}
+	return Plan{Ops: ops, Skipped: p.Skipped}
+}
+
+func contentDiff(op Op) string {
+	current, err := os.ReadFile(op.Path)
+	if err != nil || op.Content == nil {
+		return ""
+	}
+	diff, _ := textdiff.Unified("a/"+op.RelPath, "b/"+op.RelPath, string(current), *op.Content, maxUpdateDiffLines)
+	return diff
+}
+
+func applyUpdate(op Op, opts ApplyOptions, j *Journal) error {
+	if opts.DryRun {
+		return nil
+	}
+	if opts.BackupDir != "" {
+		if err := backup(op, opts.BackupDir); err != nil {
+			return fmt.Errorf("backup %s: %w", op.RelPath, err)
+		}
+	}
+
+	if op.Content == nil {
+		previous, err := os.Readlink(op.Path)
+		if err != nil {
+			return fmt.Errorf("update %s: %w", op.RelPath, err)
+		}
+		if err := os.Remove(op.Path); err != nil {
+			return fmt.Errorf("update %s: %w", op.RelPath, err)
+		}
+		if err := os.Symlink(op.Target, op.Path); err != nil {
+			// Put the old link back so the tree is left as it was.
+			_ = os.Symlink(previous, op.Path)
+			return fmt.Errorf("update %s: %w", op.RelPath, err)
+		}
+		j.Entries = append(j.Entries, JournalEntry{
+			Action:         ActionUpdate,
+			Kind:           EntrySymlink,
+			Path:           op.Path,
+			PreviousTarget: previous,
+		})
+		return nil
+	}
+
+	info, err := os.Stat(op.Path)
+	if err != nil {
+		return fmt.Errorf("update %s: %w", op.RelPath, err)
+	}
+	previous, err := os.ReadFile(op.Path)
+	if err != nil {
+		return fmt.Errorf("update %s: %w", op.RelPath, err)
+	}
+	// Write next to the file and rename so a failed write never leaves a
+	// truncated file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(op.Path), "."+filepath.Base(op.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("update %s: %w", op.RelPath, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(*op.Content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("update %s: %w", op.RelPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("update %s: %w", op.RelPath, err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("update %s: %w", op.RelPath, err)
+	}
+	if err := os.Rename(tmp.Name(), op.Path); err != nil {
+		return fmt.Errorf("update %s: %w", op.RelPath, err)
+	}
+	sum, _ := fswalk.HashFile(op.Path)
+	j.Entries = append(j.Entries, JournalEntry{
+		Action:   ActionUpdate,
+		Kind:     EntryFile,
+		Path:     op.Path,
+		Sha256:   sum,
+		Previous: previous,
+	})
+	return nil
+}
+
+// backup copies the entry an update op is about to overwrite to the same
+// relative path under dir, replacing any earlier backup.
+func backup(op Op, dir string) error {
+	dst := filepath.Join(dir, op.RelPath)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	info, err := os.Lstat(op.Path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(op.Path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+	return copyFile(op.Path, dst, info.Mode().Perm())
+}
@@ -24,6 +24,8 @@ func scanMap(node map[string]any, opts *fswalk.Options) {
 						opts.IncludeSHA256 = true
 					case "content":
 						opts.IncludeContent = true
+					case "mode":
+						opts.IncludeMode = true
 					}
 				}
 				for _, child := range props {
@@ -0,0 +1,100 @@
+package lint
+
+// token is one element of a glob: a wildcard star, or a set of bytes that a
+// single character may be.
+type token struct {
+	star bool
+	set  [256]bool
+}
+
+// parseGlob splits a glob into tokens with the syntax GlobToRegex accepts:
+// "*", "?", "[...]" (with "!" negation and ranges) and literal bytes.
+func parseGlob(glob string) []token {
+	var tokens []token
+	for i := 0; i < len(glob); i++ {
+		var t token
+		switch c := glob[i]; c {
+		case '*':
+			t.star = true
+		case '?':
+			for b := range t.set {
+				t.set[b] = true
+			}
+		case '[':
+			j := i + 1
+			negate := j < len(glob) && glob[j] == '!'
+			if negate {
+				j++
+			}
+			start := j
+			if j < len(glob) && glob[j] == ']' {
+				j++
+			}
+			for j < len(glob) && glob[j] != ']' {
+				j++
+			}
+			if j >= len(glob) {
+				// Unclosed; expand rejects it before lint runs.
+				t.set[c] = true
+				break
+			}
+			class := glob[start:j]
+			for k := 0; k < len(class); k++ {
+				if k+2 < len(class) && class[k+1] == '-' {
+					for b := int(class[k]); b <= int(class[k+2]); b++ {
+						t.set[b] = true
+					}
+					k += 2
+					continue
+				}
+				t.set[class[k]] = true
+			}
+			if negate {
+				for b := range t.set {
+					t.set[b] = !t.set[b]
+				}
+			}
+			i = j
+		default:
+			t.set[c] = true
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+// globsOverlap reports whether some name matches both globs.
+func globsOverlap(a, b string) bool {
+	ta, tb := parseGlob(a), parseGlob(b)
+	seen := map[[2]int]bool{}
+	var walk func(i, j int) bool
+	walk = func(i, j int) bool {
+		if seen[[2]int{i, j}] {
+			return false
+		}
+		seen[[2]int{i, j}] = true
+		switch {
+		case i == len(ta) && j == len(tb):
+			return true
+		case i < len(ta) && ta[i].star:
+			// The star matches nothing, or the next character of b.
+			return walk(i+1, j) || (j < len(tb) && walk(i, j+1))
+		case j < len(tb) && tb[j].star:
+			return walk(i, j+1) || (i < len(ta) && walk(i+1, j))
+		case i == len(ta) || j == len(tb):
+			return false
+		default:
+			return intersects(ta[i].set, tb[j].set) && walk(i+1, j+1)
+		}
+	}
+	return walk(0, 0)
+}
+
+func intersects(a, b [256]bool) bool {
+	for i := range a {
+		if a[i] && b[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+// Package lint reports likely mistakes in DSL specs that expand without
+// error.
+package lint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dirschema/internal/expand"
+)
+
+// Rule ids.
+const (
+	RuleOverlappingGlobs = "overlapping-globs"
+	RuleShadowedLiteral  = "shadowed-literal"
+	RuleGlobMatchesDirs  = "glob-matches-dirs"
+	RuleSizeRange        = "size-range"
+	RuleContentSHA256    = "content-sha256"
+	RuleSlashDuplicate   = "slash-duplicate"
+)
+
+// Rule describes a lint rule.
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Rules lists every rule, all of which are enabled by default.
+var Rules = []Rule{
+	{RuleOverlappingGlobs, "two globs in a directory can match the same name, so both schemas apply to it"},
+	{RuleShadowedLiteral, "a literal entry is also matched by a sibling glob, so both schemas apply to it"},
+	{RuleGlobMatchesDirs, "a file glob also matches directory names (e.g. \"*\"), so directories must satisfy a file schema"},
+	{RuleSizeRange, "size.min is greater than size.max, so no file can match"},
+	{RuleContentSHA256, "content and sha256 are both given but the hash is not the content's, so no file can match"},
+	{RuleSlashDuplicate, "a file and a directory have the same name, which cannot both exist"},
+}
+
+// Finding is one reported problem.
+type Finding struct {
+	Rule    string `json:"rule"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// KnownRule reports whether id names a rule.
+func KnownRule(id string) bool {
+	for _, rule := range Rules {
+		if rule.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint checks a DSL spec with the enabled rules (all rules when enabled is
+// nil). Name rules look at the parsed DSL, so list and dict forms lint
+// alike, and descriptor rules at the expanded schema. Findings are sorted by
+// path.
+func Lint(root any, enabled map[string]bool) ([]Finding, error) {
+	parsed, err := expand.ParseDSL(root, expand.ParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+	delete(parsed, expand.VarsKey)
+	schema, err := expand.ExpandDSL(root)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{enabled: enabled}
+	l.dir("", parsed, schema)
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].Path != l.findings[j].Path {
+			return l.findings[i].Path < l.findings[j].Path
+		}
+		return l.findings[i].Rule < l.findings[j].Rule
+	})
+	return l.findings, nil
+}
+
+type linter struct {
+	enabled  map[string]bool
+	findings []Finding
+}
+
+func (l *linter) report(rule, path, format string, args ...any) {
+	if l.enabled != nil && !l.enabled[rule] {
+		return
+	}
+	if path == "" {
+		path = "."
+	}
+	l.findings = append(l.findings, Finding{Rule: rule, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// dir checks the entries of a parsed DSL directory and, through schema,
+// their expanded schemas.
+func (l *linter) dir(path string, node, schema map[string]any) {
+	names := make([]string, 0, len(node))
+	for name, value := range node {
+		// String values annotate the directory (severity, copyFrom).
+		if _, ok := value.(string); !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var globs, literals []string
+	for _, name := range names {
+		if expand.IsGlobPattern(name) {
+			globs = append(globs, name)
+		} else {
+			literals = append(literals, name)
+		}
+	}
+
+	regexes := map[string]string{}
+	for i, a := range globs {
+		for _, b := range globs[i+1:] {
+			if globsOverlap(a, b) {
+				l.report(RuleOverlappingGlobs, path, "globs %q and %q can match the same entry", a, b)
+			}
+		}
+		if !strings.HasSuffix(a, "/") && globsOverlap(a, "*/") {
+			l.report(RuleGlobMatchesDirs, path+a, "file glob %q also matches directories", a)
+		}
+		pattern, err := expand.GlobToRegex(a)
+		if err != nil {
+			continue
+		}
+		regexes[a] = pattern
+		re := regexp.MustCompile(pattern)
+		for _, name := range literals {
+			// Directories under a file glob are reported as glob-matches-dirs.
+			if strings.HasSuffix(name, "/") && !strings.HasSuffix(a, "/") {
+				continue
+			}
+			if re.MatchString(name) {
+				l.report(RuleShadowedLiteral, path+name, "%q is also matched by glob %q", name, a)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	patternProperties, _ := schema["patternProperties"].(map[string]any)
+	for _, name := range names {
+		entrySchema, _ := properties[name].(map[string]any)
+		if pattern, ok := regexes[name]; ok {
+			entrySchema, _ = patternProperties[pattern].(map[string]any)
+		}
+		if !strings.HasSuffix(name, "/") {
+			l.file(path+name, entrySchema)
+			continue
+		}
+		if _, ok := node[strings.TrimSuffix(name, "/")]; ok {
+			l.report(RuleSlashDuplicate, path+name, "%q is listed both as a file and as a directory", strings.TrimSuffix(name, "/"))
+		}
+		child, _ := node[name].(map[string]any)
+		if _, raw := child[expand.RawSchemaKey]; raw && len(child) == 1 {
+			continue
+		}
+		l.dir(path+name, child, entrySchema)
+	}
+}
+
+// file applies the descriptor rules to an expanded file schema.
+func (l *linter) file(path string, node map[string]any) {
+	props, _ := node["properties"].(map[string]any)
+	if size, ok := props["size"].(map[string]any); ok {
+		min, hasMin := number(size["minimum"])
+		max, hasMax := number(size["maximum"])
+		if hasMin && hasMax && min > max {
+			l.report(RuleSizeRange, path, "size.min %d is greater than size.max %d", min, max)
+		}
+	}
+	content, hasContent := constString(props["content"])
+	sum, hasSum := constString(props["sha256"])
+	if hasContent && hasSum {
+		actual := sha256.Sum256([]byte(content))
+		if !strings.EqualFold(hex.EncodeToString(actual[:]), sum) {
+			l.report(RuleContentSHA256, path, "sha256 %s is not the hash of the content", sum)
+		}
+	}
+}
+
+func constString(schema any) (string, bool) {
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	s, ok := obj["const"].(string)
+	return s, ok
+}
+
+func number(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
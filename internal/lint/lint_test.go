@@ -0,0 +1,102 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintReportsRules(t *testing.T) {
+	root := map[string]any{
+		"severity": "warning",
+		"src/": map[string]any{
+			"*.go":      true,
+			"*_test.go": true,
+			"main.go":   true,
+		},
+		"*":      true,
+		"build":  true,
+		"build/": map[string]any{},
+		"data.bin": map[string]any{
+			"size": map[string]any{"min": 10.0, "max": 1.0},
+		},
+		"hello.txt": map[string]any{
+			"content": "hello\n",
+			"sha256":  "0000",
+		},
+		"ok.txt": map[string]any{
+			"content": "hello\n",
+			"sha256":  "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		},
+	}
+
+	findings, err := Lint(root, nil)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	var got [][2]string
+	for _, f := range findings {
+		got = append(got, [2]string{f.Path, f.Rule})
+	}
+	want := [][2]string{
+		{"*", RuleGlobMatchesDirs},
+		{"build", RuleShadowedLiteral},
+		{"build/", RuleSlashDuplicate},
+		{"data.bin", RuleShadowedLiteral},
+		{"data.bin", RuleSizeRange},
+		{"hello.txt", RuleContentSHA256},
+		{"hello.txt", RuleShadowedLiteral},
+		{"ok.txt", RuleShadowedLiteral},
+		{"src/", RuleOverlappingGlobs},
+		{"src/main.go", RuleShadowedLiteral},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("findings mismatch:\ngot:  %v\nwant: %v", got, want)
+	}
+
+	only, err := Lint(root, map[string]bool{RuleSizeRange: true})
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	if len(only) != 1 || only[0].Rule != RuleSizeRange {
+		t.Fatalf("expected only the size-range finding, got %#v", only)
+	}
+}
+
+func TestLintListForm(t *testing.T) {
+	root := []any{
+		map[string]any{"src/": []any{"*.go", "main.go"}},
+	}
+	findings, err := Lint(root, nil)
+	if err != nil {
+		t.Fatalf("Lint: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Path != "src/main.go" || findings[0].Rule != RuleShadowedLiteral {
+		t.Fatalf("unexpected findings: %#v", findings)
+	}
+}
+
+func TestGlobsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"*.go", "*_test.go", true},
+		{"test_*", "*.py", true},
+		{"*.go", "*.py", false},
+		{"[a-c]*", "d*", false},
+		{"[!a]*", "a*", false},
+		{"[!a]*", "b?", true},
+		{"?.txt", "ab.txt", false},
+		{"*", "*/", true},
+		{"*.go", "*/", false},
+		{"src?/", "*/", true},
+	}
+	for _, tc := range tests {
+		if got := globsOverlap(tc.a, tc.b); got != tc.want {
+			t.Errorf("globsOverlap(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+		if got := globsOverlap(tc.b, tc.a); got != tc.want {
+			t.Errorf("globsOverlap(%q, %q) = %v, want %v", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
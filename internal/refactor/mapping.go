@@ -0,0 +1,173 @@
+// Package refactor plans moves that reorganize a tree according to a
+// mapping of old glob patterns to new paths.
+package refactor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rule maps paths matching a glob to a new path. Each wildcard in From is a
+// capture that To can reference as $1, $2, ...; "$$" is a literal "$".
+//
+// In From, "*" and "?" match within a path segment, "**" matches across
+// segments and "[...]" matches a character class.
+type Rule struct {
+	From string
+	To   string
+
+	re       *regexp.Regexp
+	captures int
+}
+
+// ParseRule parses a single "FROM -> TO" mapping line.
+func ParseRule(line string) (Rule, error) {
+	from, to, ok := strings.Cut(line, "->")
+	if !ok {
+		return Rule{}, fmt.Errorf("mapping %q: expected FROM -> TO", line)
+	}
+	rule := Rule{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}
+	if rule.From == "" || rule.To == "" {
+		return Rule{}, fmt.Errorf("mapping %q: FROM and TO must be non-empty", line)
+	}
+
+	pattern, captures, err := globToCaptureRegex(rule.From)
+	if err != nil {
+		return Rule{}, fmt.Errorf("mapping %q: %w", line, err)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("mapping %q: %w", line, err)
+	}
+	rule.re = re
+	rule.captures = captures
+
+	if _, err := expand(rule.To, make([]string, captures+1)); err != nil {
+		return Rule{}, fmt.Errorf("mapping %q: %w", line, err)
+	}
+	return rule, nil
+}
+
+// ParseMapping parses one rule per line. Blank lines and lines starting
+// with "#" are ignored. Rules are tried in order; the first match wins.
+func ParseMapping(data []byte) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("mapping has no rules")
+	}
+	return rules, nil
+}
+
+// LoadMapping reads and parses a mapping file.
+func LoadMapping(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMapping(data)
+}
+
+// Map returns the new path for a slash-separated relative path, if the rule
+// matches it.
+func (r Rule) Map(rel string) (string, bool) {
+	match := r.re.FindStringSubmatch(rel)
+	if match == nil {
+		return "", false
+	}
+	out, err := expand(r.To, match)
+	if err != nil {
+		return "", false
+	}
+	return out, true
+}
+
+func globToCaptureRegex(glob string) (string, int, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	captures := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString("(.*)")
+				i++
+			} else {
+				b.WriteString("([^/]*)")
+			}
+			captures++
+		case '?':
+			b.WriteString("([^/])")
+			captures++
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", 0, fmt.Errorf("unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("([" + class + "])")
+			captures++
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String(), captures, nil
+}
+
+// expand substitutes $N references in template with groups[N].
+func expand(template string, groups []string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '$' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+1 < len(template) && template[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(template) && template[j] >= '0' && template[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			return "", fmt.Errorf("expected capture number after $ in %q", template)
+		}
+		n, _ := strconv.Atoi(template[i+1 : j])
+		if n == 0 || n >= len(groups) {
+			return "", fmt.Errorf("capture $%d out of range (pattern has %d)", n, len(groups)-1)
+		}
+		b.WriteString(groups[n])
+		i = j - 1
+	}
+	return b.String(), nil
+}
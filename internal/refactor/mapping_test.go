@@ -0,0 +1,53 @@
+package refactor
+
+import "testing"
+
+func TestRuleMap(t *testing.T) {
+	tests := []struct {
+		rule string
+		in   string
+		out  string
+		ok   bool
+	}{
+		{"lib/*.py -> src/pkg/$1.py", "lib/util.py", "src/pkg/util.py", true},
+		{"lib/*.py -> src/pkg/$1.py", "lib/sub/util.py", "", false},
+		{"docs/**/*.txt -> doc/$1/$2.md", "docs/a/b/readme.txt", "doc/a/b/readme.md", true},
+		{"test_?.go -> tests/$1_test.go", "test_a.go", "tests/a_test.go", true},
+		{"[!a]*.cfg -> conf/$1$2.cfg", "bcd.cfg", "conf/bcd.cfg", true},
+		{"price.txt -> $$price.txt", "price.txt", "$price.txt", true},
+	}
+	for _, tc := range tests {
+		rule, err := ParseRule(tc.rule)
+		if err != nil {
+			t.Fatalf("ParseRule(%q): %v", tc.rule, err)
+		}
+		out, ok := rule.Map(tc.in)
+		if ok != tc.ok || out != tc.out {
+			t.Fatalf("%q.Map(%q) = %q, %v; want %q, %v", tc.rule, tc.in, out, ok, tc.out, tc.ok)
+		}
+	}
+}
+
+func TestParseMappingErrors(t *testing.T) {
+	invalid := []string{
+		"lib/*.py src/$1.py",
+		"lib/*.py -> src/$2.py",
+		"lib/*.py -> src/$.py",
+		"lib/[ab.py -> src/x.py",
+		" -> src/x.py",
+		"# only a comment\n",
+	}
+	for _, data := range invalid {
+		if _, err := ParseMapping([]byte(data)); err == nil {
+			t.Fatalf("expected error for %q", data)
+		}
+	}
+
+	rules, err := ParseMapping([]byte("# move sources\nlib/*.py -> src/$1.py\n\ndocs/* -> doc/$1\n"))
+	if err != nil {
+		t.Fatalf("ParseMapping: %v", err)
+	}
+	if len(rules) != 2 || rules[1].From != "docs/*" || rules[1].To != "doc/$1" {
+		t.Fatalf("unexpected rules: %#v", rules)
+	}
+}
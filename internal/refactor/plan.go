@@ -0,0 +1,201 @@
+package refactor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dirschema/internal/hydrate"
+)
+
+type move struct {
+	from, to string
+}
+
+// BuildPlan maps every file and symlink under root through rules and plans
+// a move op for each one whose path changes. Moves are ordered so that a
+// path is vacated before another entry moves into it.
+//
+// Two entries mapped to the same path, a destination that already exists
+// and is not itself moved away, and moves that form a cycle are reported as
+// conflict ops instead.
+func BuildPlan(root string, rules []Rule) (hydrate.Plan, error) {
+	files, err := listFiles(root)
+	if err != nil {
+		return hydrate.Plan{}, err
+	}
+
+	var moves []move
+	for _, rel := range files {
+		for _, rule := range rules {
+			to, ok := rule.Map(rel)
+			if !ok {
+				continue
+			}
+			to = filepath.ToSlash(filepath.Clean(to))
+			if strings.HasPrefix(to, "../") || to == ".." || filepath.IsAbs(to) {
+				return hydrate.Plan{}, fmt.Errorf("mapping %s -> %s leaves the root", rel, to)
+			}
+			if to != rel {
+				moves = append(moves, move{from: rel, to: to})
+			}
+			break
+		}
+	}
+
+	byDest := map[string][]move{}
+	for _, m := range moves {
+		byDest[m.to] = append(byDest[m.to], m)
+	}
+
+	var ops []hydrate.Op
+	valid := map[string]move{}
+	for _, m := range moves {
+		if len(byDest[m.to]) == 1 {
+			valid[m.from] = m
+			continue
+		}
+		if byDest[m.to][0] == m {
+			var froms []string
+			for _, other := range byDest[m.to] {
+				froms = append(froms, other.from)
+			}
+			ops = append(ops, conflict(root, m.to, hydrate.ConflictCollision,
+				"targeted by "+strings.Join(froms, ", ")))
+		}
+	}
+
+	// A destination is free only if nothing exists there or its entry is
+	// moved away. Dropping a move can occupy another move's destination, so
+	// repeat until nothing changes.
+	for changed := true; changed; {
+		changed = false
+		for _, from := range sortedKeys(valid) {
+			m := valid[from]
+			if _, movedAway := valid[m.to]; movedAway {
+				continue
+			}
+			if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(m.to))); err != nil {
+				continue
+			}
+			ops = append(ops, conflict(root, m.to, hydrate.ConflictCollision,
+				"destination exists (moving "+m.from+")"))
+			delete(valid, from)
+			changed = true
+		}
+	}
+
+	ordered, cycles := orderMoves(valid)
+	for _, cycle := range cycles {
+		ops = append(ops, conflict(root, cycle[0], hydrate.ConflictCycle, strings.Join(cycle, " -> ")))
+	}
+	for _, m := range ordered {
+		ops = append(ops, hydrate.Op{
+			Kind:    hydrate.OpMove,
+			Path:    filepath.Join(root, filepath.FromSlash(m.to)),
+			RelPath: m.to,
+			Source:  filepath.Join(root, filepath.FromSlash(m.from)),
+			From:    m.from,
+		})
+	}
+	return hydrate.Plan{Ops: ops}, nil
+}
+
+func sortedKeys(moves map[string]move) []string {
+	keys := make([]string, 0, len(moves))
+	for key := range moves {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// orderMoves returns the moves so that each runs after the move vacating
+// its destination. Moves that loop back on themselves are returned as
+// cycles instead. Destinations are unique, so no other move can lead into
+// a cycle.
+func orderMoves(moves map[string]move) (ordered []move, cycles [][]string) {
+	const (
+		unvisited = iota
+		visiting
+		done
+		stuck
+	)
+	state := map[string]int{}
+
+	// visit reports whether the move from "from" can be applied.
+	var visit func(from string, path []string) bool
+	visit = func(from string, path []string) bool {
+		switch state[from] {
+		case done:
+			return true
+		case stuck:
+			return false
+		case visiting:
+			start := 0
+			for i, p := range path {
+				if p == from {
+					start = i
+				}
+			}
+			cycles = append(cycles, append(append([]string{}, path[start:]...), from))
+			for _, p := range path[start:] {
+				state[p] = stuck
+			}
+			return false
+		}
+		state[from] = visiting
+		m := moves[from]
+		if _, vacated := moves[m.to]; vacated && !visit(m.to, append(path, from)) {
+			return false
+		}
+		state[from] = done
+		ordered = append(ordered, m)
+		return true
+	}
+	for _, from := range sortedKeys(moves) {
+		visit(from, nil)
+	}
+	return ordered, cycles
+}
+
+func conflict(root, rel string, reason hydrate.ConflictReason, detail string) hydrate.Op {
+	return hydrate.Op{
+		Kind:    hydrate.OpConflict,
+		Path:    filepath.Join(root, filepath.FromSlash(rel)),
+		RelPath: rel,
+		Reason:  reason,
+		Detail:  detail,
+	}
+}
+
+// listFiles returns the slash-separated paths of all files and symlinks
+// under root, skipping .git directories.
+func listFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", root, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
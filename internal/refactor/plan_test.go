@@ -0,0 +1,101 @@
+package refactor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dirschema/internal/hydrate"
+)
+
+func writeTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, rel := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(rel), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+}
+
+func mustRules(t *testing.T, mapping string) []Rule {
+	t.Helper()
+	rules, err := ParseMapping([]byte(mapping))
+	if err != nil {
+		t.Fatalf("ParseMapping: %v", err)
+	}
+	return rules
+}
+
+func TestBuildPlanOrdersChainsAndApplies(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "lib/a.py", "lib/b.py", "old/x.txt", "new/x.txt", ".git/config")
+
+	// new/x.txt moves away before old/x.txt takes its place.
+	rules := mustRules(t, "lib/*.py -> src/pkg/$1.py\nnew/*.txt -> archive/$1.txt\nold/*.txt -> new/$1.txt\n")
+	plan, err := BuildPlan(root, rules)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := strings.Join([]string{
+		"move lib/a.py -> src/pkg/a.py",
+		"move lib/b.py -> src/pkg/b.py",
+		"move new/x.txt -> archive/x.txt",
+		"move old/x.txt -> new/x.txt",
+	}, "\n")
+	if got := hydrate.FormatOpsText(plan); got != want {
+		t.Fatalf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := hydrate.Apply(plan, hydrate.ApplyOptions{}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	for rel, content := range map[string]string{
+		"src/pkg/a.py":  "lib/a.py",
+		"archive/x.txt": "new/x.txt",
+		"new/x.txt":     "old/x.txt",
+	} {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil || string(data) != content {
+			t.Fatalf("unexpected %s: %q, %v", rel, data, err)
+		}
+	}
+}
+
+func TestBuildPlanReportsCollisionsAndCycles(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, "a/one.txt", "b/one.txt", "keep.txt", "x.cfg", "p", "q", "r")
+
+	rules := mustRules(t, strings.Join([]string{
+		"*/one.txt -> merged.txt",
+		"x.cfg -> keep.txt",
+		"p -> q",
+		"q -> p",
+		"r -> p",
+	}, "\n"))
+	plan, err := BuildPlan(root, rules)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := strings.Join([]string{
+		"conflict merged.txt (collision: targeted by a/one.txt, b/one.txt)",
+		"conflict p (collision: targeted by q, r)",
+		"conflict q (collision: destination exists (moving p))",
+		"conflict keep.txt (collision: destination exists (moving x.cfg))",
+	}, "\n")
+	if got := hydrate.FormatOpsText(plan); got != want {
+		t.Fatalf("unexpected plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	plan, err = BuildPlan(root, mustRules(t, "p -> q\nq -> r\nr -> p\n"))
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if got := hydrate.FormatOpsText(plan); got != "conflict p (move cycle: p -> q -> r -> p)" {
+		t.Fatalf("unexpected plan: %q", got)
+	}
+}
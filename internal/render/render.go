@@ -0,0 +1,215 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"dirschema/internal/expand"
+	"dirschema/internal/spec"
+)
+
+// Vars maps template variable names to values.
+type Vars map[string]any
+
+// SchemaVars returns the default variables declared on the root schema
+// (x-dirschema-vars, or a DSL vars block).
+func SchemaVars(schema map[string]any) Vars {
+	out := Vars{}
+	if raw, ok := schema[expand.VarsKeyword].(map[string]any); ok {
+		for k, v := range raw {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Merge returns a copy of base with the entries of each override applied in
+// order, later ones winning.
+func Merge(base Vars, overrides ...Vars) Vars {
+	out := Vars{}
+	for k, v := range base {
+		out[k] = v
+	}
+	for _, o := range overrides {
+		for k, v := range o {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// ParseAssignments parses name=value pairs as given to --var.
+func ParseAssignments(pairs []string) (Vars, error) {
+	out := Vars{}
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable %q (want name=value)", pair)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+// LoadFile reads variables from a YAML, JSON or Jsonnet file holding a
+// single object.
+func LoadFile(path string) (Vars, error) {
+	loaded, err := spec.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	var out Vars
+	if err := json.Unmarshal(loaded.JSON, &out); err != nil {
+		return nil, fmt.Errorf("vars file must contain an object: %w", err)
+	}
+	return out, nil
+}
+
+// Schema returns a copy of schema with templates rendered: property names
+// and required entries containing "{{" are rendered as path segments, and
+// file schemas carrying an x-dirschema-template annotation gain a content
+// const holding the rendered text. The result validates and hydrates like any other
+// schema. Referencing an undefined variable is an error.
+//
+// Names are only rendered when vars is not empty, so specs that use no
+// variables can name entries with a literal "{{". With variables, such a
+// name is written {{"{{"}}.
+func Schema(schema map[string]any, vars Vars) (map[string]any, error) {
+	r := renderer{vars: vars, names: len(vars) > 0}
+	rendered, err := r.node(schema, "")
+	if err != nil {
+		return nil, err
+	}
+	return rendered.(map[string]any), nil
+}
+
+type renderer struct {
+	vars Vars
+	// names is whether property and required names are rendered.
+	names bool
+}
+
+// name renders an entry name, or returns it as written when names are not
+// rendered.
+func (r renderer) name(name, where string) (string, error) {
+	if !r.names {
+		return name, nil
+	}
+	return Text(name, r.vars, where)
+}
+
+func (r renderer) node(node any, path string) (any, error) {
+	switch v := node.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, child := range v {
+			switch key {
+			case "properties":
+				props, ok := child.(map[string]any)
+				if !ok {
+					out[key] = child
+					continue
+				}
+				renderedProps := make(map[string]any, len(props))
+				for name, propSchema := range props {
+					renderedName, err := r.name(name, path+"/"+name)
+					if err != nil {
+						return nil, err
+					}
+					if _, dup := renderedProps[renderedName]; dup {
+						return nil, fmt.Errorf("%s: entries render to the same name %q", path, renderedName)
+					}
+					renderedChild, err := r.node(propSchema, path+"/"+renderedName)
+					if err != nil {
+						return nil, err
+					}
+					renderedProps[renderedName] = renderedChild
+				}
+				out[key] = renderedProps
+			case "required":
+				names, ok := child.([]any)
+				if !ok {
+					out[key] = child
+					continue
+				}
+				renderedNames := make([]any, len(names))
+				for i, raw := range names {
+					name, ok := raw.(string)
+					if !ok {
+						renderedNames[i] = raw
+						continue
+					}
+					renderedName, err := r.name(name, path+"/"+name)
+					if err != nil {
+						return nil, err
+					}
+					renderedNames[i] = renderedName
+				}
+				out[key] = renderedNames
+			default:
+				renderedChild, err := r.node(child, path)
+				if err != nil {
+					return nil, err
+				}
+				out[key] = renderedChild
+			}
+		}
+		if tmpl, ok := v[expand.TemplateKeyword].(string); ok {
+			content, err := Text(tmpl, r.vars, path)
+			if err != nil {
+				return nil, err
+			}
+			applyContent(out, content)
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			renderedChild, err := r.node(child, path)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = renderedChild
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// applyContent pins a file schema's content to the rendered template text.
+func applyContent(schema map[string]any, content string) {
+	props, _ := schema["properties"].(map[string]any)
+	if props == nil {
+		props = map[string]any{}
+	}
+	props["content"] = map[string]any{"const": content}
+	schema["properties"] = props
+
+	required, _ := schema["required"].([]any)
+	for _, r := range required {
+		if r == "content" {
+			return
+		}
+	}
+	schema["required"] = append(required, "content")
+}
+
+// Text renders a single template string. Strings without "{{" are returned
+// unchanged.
+func Text(text string, vars Vars, where string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(where).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", where, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, map[string]any(vars)); err != nil {
+		return "", fmt.Errorf("template %s: %w", where, err)
+	}
+	return b.String(), nil
+}
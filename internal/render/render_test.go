@@ -0,0 +1,130 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaRendersKeysAndTemplates(t *testing.T) {
+	schema := map[string]any{
+		"type":             "object",
+		"x-dirschema-vars": map[string]any{"name": "default"},
+		"properties": map[string]any{
+			"cmd/": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"{{.name}}/": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"main.go": map[string]any{
+								"type":                 "object",
+								"properties":           map[string]any{},
+								"required":             []any{},
+								"x-dirschema-template": "package main // {{.name}}\n",
+							},
+						},
+						"required": []any{"main.go"},
+					},
+				},
+				"required": []any{"{{.name}}/"},
+			},
+		},
+		"required": []any{"cmd/"},
+	}
+
+	vars := Merge(SchemaVars(schema), Vars{"name": "billing"})
+	got, err := Schema(schema, vars)
+	if err != nil {
+		t.Fatalf("Schema: %v", err)
+	}
+
+	cmd := got["properties"].(map[string]any)["cmd/"].(map[string]any)
+	if !reflect.DeepEqual(cmd["required"], []any{"billing/"}) {
+		t.Fatalf("required not rendered: %v", cmd["required"])
+	}
+	svc, ok := cmd["properties"].(map[string]any)["billing/"].(map[string]any)
+	if !ok {
+		t.Fatalf("property key not rendered: %v", cmd["properties"])
+	}
+	mainGo := svc["properties"].(map[string]any)["main.go"].(map[string]any)
+	wantProps := map[string]any{"content": map[string]any{"const": "package main // billing\n"}}
+	if !reflect.DeepEqual(mainGo["properties"], wantProps) {
+		t.Fatalf("content not rendered: %v", mainGo["properties"])
+	}
+	if !reflect.DeepEqual(mainGo["required"], []any{"content"}) {
+		t.Fatalf("content not required: %v", mainGo["required"])
+	}
+
+	// The input schema is left untouched.
+	if _, ok := schema["properties"].(map[string]any)["cmd/"].(map[string]any)["properties"].(map[string]any)["{{.name}}/"]; !ok {
+		t.Fatalf("input schema was modified")
+	}
+}
+
+func TestSchemaMissingVariable(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"{{.name}}.txt": map[string]any{}},
+		"required":   []any{"{{.name}}.txt"},
+	}
+	if _, err := Schema(schema, Vars{"other": "x"}); err == nil {
+		t.Fatalf("expected error for undefined variable")
+	}
+}
+
+func TestSchemaLiteralBracesInNames(t *testing.T) {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"{{cookiecutter.name}}/": map[string]any{}},
+		"required":   []any{"{{cookiecutter.name}}/"},
+	}
+	// Without variables, names are taken as written.
+	got, err := Schema(schema, Vars{})
+	if err != nil {
+		t.Fatalf("Schema: %v", err)
+	}
+	if !reflect.DeepEqual(got, schema) {
+		t.Fatalf("expected names left as written, got %v", got)
+	}
+
+	// With variables, a literal "{{" is escaped as {{"{{"}}.
+	escaped := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{`{{"{{"}}cookiecutter.name}}/`: map[string]any{}},
+		"required":   []any{`{{"{{"}}cookiecutter.name}}/`},
+	}
+	got, err = Schema(escaped, Vars{"name": "app"})
+	if err != nil {
+		t.Fatalf("Schema: %v", err)
+	}
+	if !reflect.DeepEqual(got, schema) {
+		t.Fatalf("expected escaped names rendered literally, got %v", got)
+	}
+}
+
+func TestParseAssignmentsAndLoadFile(t *testing.T) {
+	got, err := ParseAssignments([]string{"name=billing", "greeting=a=b"})
+	if err != nil {
+		t.Fatalf("ParseAssignments: %v", err)
+	}
+	if !reflect.DeepEqual(got, Vars{"name": "billing", "greeting": "a=b"}) {
+		t.Fatalf("unexpected vars: %v", got)
+	}
+	if _, err := ParseAssignments([]string{"novalue"}); err == nil {
+		t.Fatalf("expected error for missing =")
+	}
+
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(path, []byte("name: billing\nport: 8080\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	fileVars, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if !reflect.DeepEqual(fileVars, Vars{"name": "billing", "port": float64(8080)}) {
+		t.Fatalf("unexpected file vars: %v", fileVars)
+	}
+}
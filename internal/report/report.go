@@ -5,15 +5,17 @@ import (
 	"fmt"
 	"strings"
 
+	"dirschema/internal/baseline"
 	"dirschema/internal/hydrate"
 	"dirschema/internal/validate"
 )
 
 func FormatText(result validate.Result) string {
-	if result.Valid {
+	if len(result.Errors) == 0 {
 		return ""
 	}
 	var b strings.Builder
+	errorCount, warningCount := 0, 0
 	for i, err := range result.Errors {
 		path := err.InstancePath
 		if path == "" {
@@ -22,15 +24,90 @@ func FormatText(result validate.Result) string {
 		if i > 0 {
 			b.WriteString("\n")
 		}
-		fmt.Fprintf(&b, "%s: %s (keyword=%s, schemaPath=%s, instancePath=%s)", path, err.Message, err.Keyword, err.SchemaPath, err.InstancePath)
+		prefix := ""
+		if err.Severity == validate.SeverityWarning {
+			prefix = "warning: "
+			warningCount++
+		} else {
+			errorCount++
+		}
+		fmt.Fprintf(&b, "%s: %s%s (keyword=%s, schemaPath=%s, instancePath=%s)", path, prefix, err.Message, err.Keyword, err.SchemaPath, err.InstancePath)
+		for _, s := range err.Suggestions {
+			fmt.Fprintf(&b, " [did you mean %s for %s?]", strings.Join(s.Candidates, " or "), s.Missing)
+		}
+	}
+	if result.Truncated > 0 {
+		fmt.Fprintf(&b, "\n... %s truncated", plural(result.Truncated, "more violation"))
+	}
+	// The result's counts, when set, also cover violations truncated from
+	// the listing.
+	if result.ErrorCount+result.WarningCount > 0 {
+		errorCount, warningCount = result.ErrorCount, result.WarningCount
+	}
+	// Only summarize when warnings are present, so error-only output stays
+	// one violation per line.
+	if warningCount > 0 {
+		fmt.Fprintf(&b, "\n%s, %s", plural(errorCount, "error"), plural(warningCount, "warning"))
 	}
 	return b.String()
 }
 
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
 func FormatJSON(result validate.Result) ([]byte, error) {
 	return json.Marshal(result)
 }
 
+// BaselineResult extends a validation result with the outcome of applying a
+// baseline file.
+type BaselineResult struct {
+	validate.Result
+	Baseline BaselineSummary `json:"baseline"`
+}
+
+// BaselineSummary reports how many known violations were suppressed and
+// which baseline entries no longer occur.
+type BaselineSummary struct {
+	Suppressed int              `json:"suppressed"`
+	Fixed      []baseline.Entry `json:"fixed"`
+}
+
+func FormatBaselineJSON(result validate.Result, fixed []baseline.Entry, suppressed int) ([]byte, error) {
+	if fixed == nil {
+		fixed = []baseline.Entry{}
+	}
+	return json.Marshal(BaselineResult{
+		Result:   result,
+		Baseline: BaselineSummary{Suppressed: suppressed, Fixed: fixed},
+	})
+}
+
+// FormatBaselineText lists fixed baseline entries, one per line, followed by
+// the number of suppressed violations.
+func FormatBaselineText(fixed []baseline.Entry, suppressed int) string {
+	var b strings.Builder
+	for _, entry := range fixed {
+		path := entry.InstancePath
+		if path == "" {
+			path = "/"
+		}
+		name := ""
+		if entry.Name != "" {
+			name = ", name=" + entry.Name
+		}
+		fmt.Fprintf(&b, "%s: fixed, can be removed from baseline (keyword=%s, schemaPath=%s, instancePath=%s%s)\n", path, entry.Keyword, entry.SchemaPath, entry.InstancePath, name)
+	}
+	if suppressed > 0 {
+		fmt.Fprintf(&b, "%s suppressed by baseline", plural(suppressed, "known violation"))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
 // HydrateResult combines hydration operations with validation result
 type HydrateResult struct {
 	Ops    []hydrate.Op     `json:"ops"`
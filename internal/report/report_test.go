@@ -40,3 +40,26 @@ func TestFormatTextValid(t *testing.T) {
 		t.Fatalf("expected empty output, got %q", got)
 	}
 }
+
+func TestFormatTextSuggestions(t *testing.T) {
+	res := validate.Result{
+		Valid: false,
+		Errors: []validate.Item{
+			{
+				InstancePath: "",
+				SchemaPath:   "/required",
+				Keyword:      "required",
+				Message:      "missing properties: 'README.md'",
+				Suggestions: []validate.Suggestion{
+					{Missing: "README.md", Candidates: []string{"Readme.md", "README.txt"}},
+				},
+			},
+		},
+	}
+
+	got := FormatText(res)
+	want := "/: missing properties: 'README.md' (keyword=required, schemaPath=/required, instancePath=) [did you mean Readme.md or README.txt for README.md?]"
+	if got != want {
+		t.Fatalf("unexpected output:\n%q\nwant:\n%q", got, want)
+	}
+}
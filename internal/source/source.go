@@ -0,0 +1,107 @@
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dirschema/internal/expand"
+	"dirschema/internal/fswalk"
+)
+
+// Resolve returns a copy of schema with contentFrom/copyFrom sources made
+// absolute against baseDir (normally the spec's directory).
+//
+// File schemas that also ask to verify their source gain a sha256 or content
+// const computed from the source file, so validation compares the tree
+// against the same file hydration copies from.
+func Resolve(schema map[string]any, baseDir string) (map[string]any, error) {
+	resolved, err := resolveNode(schema, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]any), nil
+}
+
+func resolveNode(node any, baseDir string) (any, error) {
+	switch v := node.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, child := range v {
+			resolvedChild, err := resolveNode(child, baseDir)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolvedChild
+		}
+		if src, ok := v[expand.CopyFromKeyword].(string); ok {
+			out[expand.CopyFromKeyword] = absolute(src, baseDir)
+		}
+		if src, ok := v[expand.ContentFromKeyword].(string); ok {
+			abs := absolute(src, baseDir)
+			out[expand.ContentFromKeyword] = abs
+			if verify, ok := v[expand.VerifyKeyword].(string); ok {
+				if err := pinToSource(out, abs, verify); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			resolvedChild, err := resolveNode(child, baseDir)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolvedChild
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func absolute(path, baseDir string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// pinToSource adds a const for the verified attribute of the source file.
+func pinToSource(schema map[string]any, src, verify string) error {
+	var value string
+	switch verify {
+	case "sha256":
+		sum, err := fswalk.HashFile(src)
+		if err != nil {
+			return fmt.Errorf("verify source %s: %w", src, err)
+		}
+		value = sum
+	case "content":
+		contents, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("verify source %s: %w", src, err)
+		}
+		value = string(contents)
+	default:
+		return fmt.Errorf("unsupported verify mode %q", verify)
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	if props == nil {
+		props = map[string]any{}
+	}
+	props[verify] = map[string]any{"const": value}
+	schema["properties"] = props
+
+	required, _ := schema["required"].([]any)
+	for _, r := range required {
+		if r == verify {
+			return nil
+		}
+	}
+	schema["required"] = append(required, verify)
+	return nil
+}
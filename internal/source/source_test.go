@@ -0,0 +1,97 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dirschema/internal/expand"
+)
+
+func TestResolveMakesSourcesAbsolute(t *testing.T) {
+	base := t.TempDir()
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"LICENSE": map[string]any{
+				"type":                    "object",
+				"properties":              map[string]any{},
+				"required":                []any{},
+				expand.ContentFromKeyword: "texts/MIT",
+			},
+			"ci/": map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{},
+				"required":             []any{},
+				expand.CopyFromKeyword: "/abs/ci",
+			},
+		},
+		"required": []any{"LICENSE", "ci/"},
+	}
+
+	got, err := Resolve(schema, base)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	props := got["properties"].(map[string]any)
+	if src := props["LICENSE"].(map[string]any)[expand.ContentFromKeyword]; src != filepath.Join(base, "texts/MIT") {
+		t.Fatalf("unexpected contentFrom: %v", src)
+	}
+	if src := props["ci/"].(map[string]any)[expand.CopyFromKeyword]; src != "/abs/ci" {
+		t.Fatalf("unexpected copyFrom: %v", src)
+	}
+	if src := schema["properties"].(map[string]any)["LICENSE"].(map[string]any)[expand.ContentFromKeyword]; src != "texts/MIT" {
+		t.Fatalf("input schema was modified: %v", src)
+	}
+}
+
+func TestResolvePinsVerifiedSource(t *testing.T) {
+	base := t.TempDir()
+	if err := os.WriteFile(filepath.Join(base, "LICENSE"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	file := func(verify string) map[string]any {
+		return map[string]any{
+			"type":                    "object",
+			"properties":              map[string]any{},
+			"required":                []any{},
+			expand.ContentFromKeyword: "LICENSE",
+			expand.VerifyKeyword:      verify,
+		}
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"a": file("sha256"),
+			"b": file("content"),
+		},
+		"required": []any{"a", "b"},
+	}
+
+	got, err := Resolve(schema, base)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	props := got["properties"].(map[string]any)
+
+	a := props["a"].(map[string]any)
+	sha := a["properties"].(map[string]any)["sha256"].(map[string]any)["const"]
+	if sha != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("unexpected sha256 const: %v", sha)
+	}
+	if req := a["required"].([]any); len(req) != 1 || req[0] != "sha256" {
+		t.Fatalf("unexpected required: %v", req)
+	}
+
+	b := props["b"].(map[string]any)
+	if content := b["properties"].(map[string]any)["content"].(map[string]any)["const"]; content != "hello" {
+		t.Fatalf("unexpected content const: %v", content)
+	}
+
+	if _, err := Resolve(map[string]any{"properties": map[string]any{"c": map[string]any{
+		expand.ContentFromKeyword: "missing",
+		expand.VerifyKeyword:      "sha256",
+	}}}, base); err == nil {
+		t.Fatalf("expected error for missing source")
+	}
+}
@@ -0,0 +1,79 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-jsonnet/formatter"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by Marshal.
+const (
+	FormatJSON    = "json"
+	FormatYAML    = "yaml"
+	FormatJsonnet = "jsonnet"
+)
+
+// Marshal encodes a spec or schema so that Load reads it back unchanged.
+// Object keys are always sorted, so equal values encode identically. indent
+// is the number of spaces per level; 0 gives compact JSON and Jsonnet, and
+// YAML's default of 2. The output ends with a newline.
+func Marshal(value any, format string, indent int) ([]byte, error) {
+	if indent < 0 {
+		return nil, fmt.Errorf("indent must not be negative")
+	}
+	switch format {
+	case FormatJSON:
+		return marshalJSON(value, indent)
+	case FormatYAML:
+		if indent == 0 {
+			indent = 2
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(indent)
+		if err := enc.Encode(value); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatJsonnet:
+		// JSON is valid Jsonnet; the formatter keeps its line breaks and
+		// turns it into idiomatic Jsonnet with unquoted field names where
+		// possible.
+		encoded, err := marshalJSON(value, indent)
+		if err != nil {
+			return nil, err
+		}
+		opts := formatter.DefaultOptions()
+		if indent > 0 {
+			opts.Indent = indent
+		}
+		formatted, err := formatter.Format("spec.jsonnet", string(encoded), opts)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(formatted), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (want json, yaml or jsonnet)", format)
+	}
+}
+
+func marshalJSON(value any, indent int) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Globs and content are written as-is rather than as \u escapes.
+	enc.SetEscapeHTML(false)
+	if indent > 0 {
+		enc.SetIndent("", strings.Repeat(" ", indent))
+	}
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
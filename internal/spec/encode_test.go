@@ -0,0 +1,53 @@
+package spec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarshalRoundTrips(t *testing.T) {
+	value := map[string]any{
+		"src/": []any{
+			"*.go",
+			map[string]any{"run.sh": map[string]any{"mode": "0755", "size": float64(10)}},
+			map[string]any{"link": map[string]any{"symlink": "run.sh"}},
+		},
+		"README.md": map[string]any{"content": "# <title> & \"quotes\"\n"},
+		"yes":       true,
+	}
+
+	for _, format := range []string{FormatJSON, FormatYAML, FormatJsonnet} {
+		t.Run(format, func(t *testing.T) {
+			encoded, err := Marshal(value, format, 2)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			again, err := Marshal(value, format, 2)
+			if err != nil || string(again) != string(encoded) {
+				t.Fatalf("expected deterministic output, got:\n%s\nthen:\n%s", encoded, again)
+			}
+
+			path := writeFile(t, t.TempDir(), "spec."+format, string(encoded))
+			loaded, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v\n%s", err, encoded)
+			}
+			if got := decodeJSON(t, loaded.JSON); !reflect.DeepEqual(got, value) {
+				t.Fatalf("round trip mismatch:\ngot:  %#v\nwant: %#v\n%s", got, value, encoded)
+			}
+		})
+	}
+}
+
+func TestMarshalCompactJSON(t *testing.T) {
+	got, err := Marshal(map[string]any{"b": true, "a": []any{"x"}}, FormatJSON, 0)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(got) != "{\"a\":[\"x\"],\"b\":true}\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+	if _, err := Marshal(true, "toml", 0); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+}
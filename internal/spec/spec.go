@@ -233,11 +233,19 @@ func normalizeYAML(value any) (any, error) {
 	}
 }
 
+// isSchemaKeyword reports whether key is a JSON Schema keyword or one of
+// the x-dirschema- annotations expand emits, such as template vars or a
+// root severity.
 func isSchemaKeyword(key string) bool {
+	if strings.HasPrefix(key, annotationPrefix) {
+		return true
+	}
 	_, ok := schemaKeywords[key]
 	return ok
 }
 
+const annotationPrefix = "x-dirschema-"
+
 var schemaKeywords = map[string]struct{}{
 	"$schema":              {},
 	"$id":                  {},
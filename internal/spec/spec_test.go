@@ -104,6 +104,20 @@ func TestInferKind(t *testing.T) {
 		t.Fatalf("InferKind schema: got %v want %v", kind, KindSchema)
 	}
 
+	annotated := map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{},
+		"x-dirschema-vars":     map[string]any{"name": "app"},
+		"x-dirschema-severity": "warning",
+	}
+	kind, err = InferKind(annotated)
+	if err != nil {
+		t.Fatalf("InferKind annotated schema: %v", err)
+	}
+	if kind != KindSchema {
+		t.Fatalf("InferKind annotated schema: got %v want %v", kind, KindSchema)
+	}
+
 	mixed := map[string]any{"type": "object", "src/": map[string]any{}}
 	if _, err := InferKind(mixed); err == nil {
 		t.Fatalf("expected mixed spec error")
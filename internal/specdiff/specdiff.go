@@ -0,0 +1,358 @@
+// Package specdiff compares two expanded schemas and classifies what changed
+// in meaning.
+package specdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"dirschema/internal/expand"
+)
+
+// Change kinds.
+const (
+	KindEntryAdded    = "entry-added"
+	KindEntryRemoved  = "entry-removed"
+	KindGlobAdded     = "glob-added"
+	KindGlobRemoved   = "glob-removed"
+	KindTightened     = "tightened"
+	KindRelaxed       = "relaxed"
+	KindChanged       = "changed"
+	KindHydrateOnly   = "hydrate-only"
+	KindSchemaChanged = "schema-changed"
+)
+
+// Change is one difference in meaning between two schemas. A change is
+// breaking when a tree valid under the old schema may be invalid under the
+// new one.
+type Change struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	Breaking bool   `json:"breaking"`
+	Detail   string `json:"detail"`
+}
+
+// Diff compares two expanded schemas entry by entry. Changes are ordered by
+// path.
+func Diff(before, after map[string]any) []Change {
+	d := &differ{}
+	if !reflect.DeepEqual(before[expand.VarsKeyword], after[expand.VarsKeyword]) {
+		d.add(".", KindChanged, true, "template vars changed")
+	}
+	d.dir("", before, after)
+	sort.SliceStable(d.changes, func(i, j int) bool { return d.changes[i].Path < d.changes[j].Path })
+	return d.changes
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(path, kind string, breaking bool, format string, args ...any) {
+	if path == "" {
+		path = "."
+	}
+	d.changes = append(d.changes, Change{Path: path, Kind: kind, Breaking: breaking, Detail: fmt.Sprintf(format, args...)})
+}
+
+// dirKeywords are the directory keywords dir compares itself; any other
+// difference is reported as a schema change.
+var dirKeywords = map[string]bool{
+	"type": true, "properties": true, "patternProperties": true, "required": true, "allOf": true,
+	expand.SeverityKeyword: true, expand.CopyFromKeyword: true, expand.DefaultKeyword: true, expand.VarsKeyword: true,
+}
+
+// dir compares two directory schemas at path.
+func (d *differ) dir(path string, before, after map[string]any) {
+	if d.unrecognized(path, before, after, dirKeywords) {
+		return
+	}
+	d.severity(path, before, after)
+	d.hydrateOnly(path, before, after, expand.CopyFromKeyword, "copyFrom")
+	d.hydrateOnly(path, before, after, expand.DefaultKeyword, "default")
+
+	oldRequired, newRequired := requiredSet(before), requiredSet(after)
+	oldProps, newProps := objectAt(before, "properties"), objectAt(after, "properties")
+	for _, name := range unionKeys(oldProps, newProps) {
+		oldEntry, inOld := oldProps[name]
+		newEntry, inNew := newProps[name]
+		entryPath := path + name
+		switch {
+		case !inOld:
+			if newRequired[name] {
+				d.add(entryPath, KindEntryAdded, true, "required entry added")
+			} else {
+				d.add(entryPath, KindEntryAdded, false, "optional entry added")
+			}
+		case !inNew:
+			d.add(entryPath, KindEntryRemoved, false, "entry removed")
+		default:
+			if !oldRequired[name] && newRequired[name] {
+				d.add(entryPath, KindTightened, true, "entry is now required")
+			} else if oldRequired[name] && !newRequired[name] {
+				d.add(entryPath, KindRelaxed, false, "entry is no longer required")
+			}
+			d.entry(entryPath, oldEntry, newEntry)
+		}
+	}
+
+	oldPatterns, newPatterns := objectAt(before, "patternProperties"), objectAt(after, "patternProperties")
+	for _, pattern := range unionKeys(oldPatterns, newPatterns) {
+		oldEntry, inOld := oldPatterns[pattern]
+		newEntry, inNew := newPatterns[pattern]
+		entryPath := path + globName(pattern)
+		switch {
+		case !inOld:
+			// Expand requires at least one match for every glob.
+			d.add(entryPath, KindGlobAdded, true, "glob added; at least one entry must match it")
+		case !inNew:
+			d.add(entryPath, KindGlobRemoved, false, "glob removed")
+		default:
+			d.entry(entryPath, oldEntry, newEntry)
+		}
+	}
+}
+
+// entry compares the schemas of an entry present in both versions.
+func (d *differ) entry(path string, before, after any) {
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	oldObj, okOld := before.(map[string]any)
+	newObj, okNew := after.(map[string]any)
+	if !okOld || !okNew {
+		d.add(path, KindSchemaChanged, true, "schema changed")
+		return
+	}
+	if strings.HasSuffix(path, "/") {
+		d.dir(path, oldObj, newObj)
+		return
+	}
+	d.file(path, oldObj, newObj)
+}
+
+// fileKeywords are the file keywords file compares itself.
+var fileKeywords = map[string]bool{
+	"oneOf": true, "type": true, "properties": true, "required": true,
+	expand.SeverityKeyword: true, expand.TemplateKeyword: true, expand.ContentFromKeyword: true,
+	expand.VerifyKeyword: true, expand.DefaultKeyword: true,
+}
+
+// file compares two file schemas at path.
+func (d *differ) file(path string, before, after map[string]any) {
+	if d.unrecognized(path, before, after, fileKeywords) {
+		return
+	}
+	d.severity(path, before, after)
+	d.hydrateOnly(path, before, after, expand.ContentFromKeyword, "contentFrom")
+	d.hydrateOnly(path, before, after, expand.DefaultKeyword, "default")
+	d.pinned(path, before, after, expand.TemplateKeyword, "template")
+	d.pinned(path, before, after, expand.VerifyKeyword, "verify")
+
+	oldProps, newProps := objectAt(before, "properties"), objectAt(after, "properties")
+	for _, attr := range []string{"content", "sha256", "mode", "symlink"} {
+		oldConst, inOld := constValue(oldProps[attr])
+		newConst, inNew := constValue(newProps[attr])
+		switch {
+		case !inOld && !inNew:
+		case !inOld:
+			d.add(path, KindTightened, true, "%s pinned to %s", attr, short(newConst))
+		case !inNew:
+			d.add(path, KindRelaxed, false, "%s no longer pinned", attr)
+		case !reflect.DeepEqual(oldConst, newConst):
+			d.add(path, KindChanged, true, "%s changed from %s to %s", attr, short(oldConst), short(newConst))
+		}
+	}
+	d.size(path, oldProps["size"], newProps["size"])
+}
+
+// size compares size constraints as ranges.
+func (d *differ) size(path string, before, after any) {
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	oldMin, oldMax := sizeRange(before)
+	newMin, newMax := sizeRange(after)
+	detail := fmt.Sprintf("size changed from %s to %s", formatRange(oldMin, oldMax), formatRange(newMin, newMax))
+	switch {
+	case oldMin == newMin && oldMax == newMax:
+	case newMin <= oldMin && newMax >= oldMax:
+		d.add(path, KindRelaxed, false, "%s", detail)
+	case newMin >= oldMin && newMax <= oldMax:
+		d.add(path, KindTightened, true, "%s", detail)
+	default:
+		d.add(path, KindChanged, true, "%s", detail)
+	}
+}
+
+// severity compares severity annotations; error is the default.
+func (d *differ) severity(path string, before, after map[string]any) {
+	oldSev, newSev := severityOf(before), severityOf(after)
+	switch {
+	case oldSev == newSev:
+	case newSev == "warning":
+		d.add(path, KindRelaxed, false, "severity lowered to warning")
+	default:
+		d.add(path, KindTightened, true, "severity raised to error")
+	}
+}
+
+// pinned compares an annotation that constrains validation: adding or
+// changing it is breaking, removing it is not.
+func (d *differ) pinned(path string, before, after map[string]any, keyword, name string) {
+	oldVal, inOld := before[keyword]
+	newVal, inNew := after[keyword]
+	switch {
+	case reflect.DeepEqual(oldVal, newVal):
+	case !inOld:
+		d.add(path, KindTightened, true, "%s added", name)
+	case !inNew:
+		d.add(path, KindRelaxed, false, "%s removed", name)
+	default:
+		d.add(path, KindChanged, true, "%s changed", name)
+	}
+}
+
+// hydrateOnly reports a change to an annotation that only hydrate reads.
+func (d *differ) hydrateOnly(path string, before, after map[string]any, keyword, name string) {
+	oldVal, newVal := before[keyword], after[keyword]
+	if reflect.DeepEqual(oldVal, newVal) {
+		return
+	}
+	d.add(path, KindHydrateOnly, false, "%s changed from %s to %s", name, short(oldVal), short(newVal))
+}
+
+// unrecognized reports a schema change when the schemas differ in keywords
+// outside known, so that raw schemas are still compared. It returns true
+// when it did.
+func (d *differ) unrecognized(path string, before, after map[string]any, known map[string]bool) bool {
+	for _, key := range unionKeys(before, after) {
+		if known[key] {
+			continue
+		}
+		if !reflect.DeepEqual(before[key], after[key]) {
+			d.add(path, KindSchemaChanged, true, "schema changed (%s)", key)
+			return true
+		}
+	}
+	return false
+}
+
+func severityOf(schema map[string]any) string {
+	if sev, ok := schema[expand.SeverityKeyword].(string); ok {
+		return sev
+	}
+	return "error"
+}
+
+func requiredSet(schema map[string]any) map[string]bool {
+	out := map[string]bool{}
+	list, _ := schema["required"].([]any)
+	for _, name := range list {
+		if s, ok := name.(string); ok {
+			out[s] = true
+		}
+	}
+	return out
+}
+
+func objectAt(schema map[string]any, key string) map[string]any {
+	obj, _ := schema[key].(map[string]any)
+	return obj
+}
+
+func constValue(schema any) (any, bool) {
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	value, ok := obj["const"]
+	return value, ok
+}
+
+// sizeRange returns the sizes a size schema accepts.
+func sizeRange(schema any) (float64, float64) {
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		return 0, math.Inf(1)
+	}
+	if c, ok := toFloat(obj["const"]); ok {
+		return c, c
+	}
+	min, ok := toFloat(obj["minimum"])
+	if !ok {
+		min = 0
+	}
+	max, ok := toFloat(obj["maximum"])
+	if !ok {
+		max = math.Inf(1)
+	}
+	return min, max
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func formatRange(min, max float64) string {
+	switch {
+	case min == max:
+		return fmt.Sprintf("%g", min)
+	case math.IsInf(max, 1):
+		return fmt.Sprintf("%g..", min)
+	default:
+		return fmt.Sprintf("%g..%g", min, max)
+	}
+}
+
+// globName shows a pattern as the glob it was expanded from.
+func globName(pattern string) string {
+	if glob, ok := expand.RegexToGlob(pattern); ok {
+		return glob
+	}
+	return pattern
+}
+
+// short quotes a value for a message, eliding long ones.
+func short(value any) string {
+	if value == nil {
+		return "none"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	s := string(encoded)
+	if len(s) > 40 {
+		s = s[:37] + "..."
+	}
+	return s
+}
+
+func unionKeys(a, b map[string]any) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, m := range []map[string]any{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				out = append(out, key)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
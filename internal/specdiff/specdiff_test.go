@@ -0,0 +1,80 @@
+package specdiff
+
+import (
+	"reflect"
+	"testing"
+
+	"dirschema/internal/expand"
+)
+
+func expandDSL(t *testing.T, dsl map[string]any) map[string]any {
+	t.Helper()
+	schema, err := expand.ExpandDSL(dsl)
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+	return schema
+}
+
+func TestDiffClassifiesChanges(t *testing.T) {
+	before := expandDSL(t, map[string]any{
+		"README.md": true,
+		"old.txt":   true,
+		"src/": map[string]any{
+			"*.go":   true,
+			"*.py":   true,
+			"run.sh": map[string]any{"mode": "0755"},
+		},
+		"data.bin": map[string]any{"size": map[string]any{"min": 1, "max": 100}},
+		"big.bin":  map[string]any{"size": map[string]any{"min": 1, "max": 100}},
+		"NOTES":    true,
+		"docs/":    map[string]any{"severity": "warning"},
+	})
+	after := expandDSL(t, map[string]any{
+		"README.md": map[string]any{"content": "hi\n"},
+		"LICENSE":   true,
+		"src/": map[string]any{
+			"*.go":   true,
+			"*.rs":   true,
+			"run.sh": true,
+		},
+		"data.bin": map[string]any{"size": map[string]any{"min": 10, "max": 50}},
+		"big.bin":  map[string]any{"size": map[string]any{"min": 0, "max": 1000}},
+		"NOTES":    map[string]any{"severity": "warning"},
+		"docs/":    map[string]any{},
+	})
+
+	var got []Change
+	for _, c := range Diff(before, after) {
+		got = append(got, Change{Path: c.Path, Kind: c.Kind, Breaking: c.Breaking})
+	}
+	want := []Change{
+		{Path: "LICENSE", Kind: KindEntryAdded, Breaking: true},
+		{Path: "NOTES", Kind: KindRelaxed, Breaking: false},
+		{Path: "README.md", Kind: KindTightened, Breaking: true},
+		{Path: "big.bin", Kind: KindRelaxed, Breaking: false},
+		{Path: "data.bin", Kind: KindTightened, Breaking: true},
+		{Path: "docs/", Kind: KindTightened, Breaking: true},
+		{Path: "old.txt", Kind: KindEntryRemoved, Breaking: false},
+		{Path: "src/*.py", Kind: KindGlobRemoved, Breaking: false},
+		{Path: "src/*.rs", Kind: KindGlobAdded, Breaking: true},
+		{Path: "src/run.sh", Kind: KindRelaxed, Breaking: false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestDiffIdenticalAndRawSchemas(t *testing.T) {
+	schema := expandDSL(t, map[string]any{"a/": map[string]any{"b.txt": true}})
+	if changes := Diff(schema, schema); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+
+	before := expandDSL(t, map[string]any{"config.json": map[string]any{"rawSchema": map[string]any{"type": "object"}}})
+	after := expandDSL(t, map[string]any{"config.json": map[string]any{"rawSchema": map[string]any{"type": "object", "minProperties": 1}}})
+	changes := Diff(before, after)
+	if len(changes) != 1 || changes[0].Kind != KindSchemaChanged || !changes[0].Breaking {
+		t.Fatalf("expected one breaking schema change, got %+v", changes)
+	}
+}
@@ -0,0 +1,97 @@
+package suggest
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// MaxCandidates bounds the number of suggestions returned for a single name.
+const MaxCandidates = 3
+
+// Candidates returns sibling entry names that look like a misspelling of
+// missing, best match first.
+//
+// A sibling is a candidate when it has the same kind as missing (directory
+// keys end in "/") and either matches case-insensitively, shares the stem
+// with a different extension (README.md vs README.markdown), or is within a
+// small edit distance.
+func Candidates(missing string, siblings []string) []string {
+	isDir := strings.HasSuffix(missing, "/")
+	name := strings.ToLower(strings.TrimSuffix(missing, "/"))
+	stem := stemOf(name)
+	threshold := len(name) / 4
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	type scored struct {
+		name  string
+		score int
+	}
+	var found []scored
+	for _, sib := range siblings {
+		if sib == missing || strings.HasSuffix(sib, "/") != isDir {
+			continue
+		}
+		other := strings.ToLower(strings.TrimSuffix(sib, "/"))
+		switch {
+		case other == name:
+			found = append(found, scored{sib, 0})
+		case stemOf(other) == stem:
+			found = append(found, scored{sib, 1})
+		default:
+			if d := distance(name, other); d <= threshold {
+				found = append(found, scored{sib, 1 + d})
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].score == found[j].score {
+			return found[i].name < found[j].name
+		}
+		return found[i].score < found[j].score
+	})
+
+	if len(found) > MaxCandidates {
+		found = found[:MaxCandidates]
+	}
+	out := make([]string, 0, len(found))
+	for _, f := range found {
+		out = append(out, f.name)
+	}
+	return out
+}
+
+// stemOf returns name without its extension. A dotfile such as .gitignore
+// has no extension and is its own stem.
+func stemOf(name string) string {
+	ext := path.Ext(name)
+	if ext == name {
+		return name
+	}
+	return strings.TrimSuffix(name, ext)
+}
+
+// distance returns the Levenshtein edit distance between a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,67 @@
+package suggest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		name     string
+		missing  string
+		siblings []string
+		want     []string
+	}{
+		{
+			name:     "case fold",
+			missing:  "README.md",
+			siblings: []string{"Readme.md", "main.go"},
+			want:     []string{"Readme.md"},
+		},
+		{
+			name:     "extension variant",
+			missing:  "README.md",
+			siblings: []string{"README.markdown", "LICENSE"},
+			want:     []string{"README.markdown"},
+		},
+		{
+			name:     "edit distance",
+			missing:  "Makefile",
+			siblings: []string{"Makfile", "go.mod"},
+			want:     []string{"Makfile"},
+		},
+		{
+			name:     "kind must match",
+			missing:  "docs/",
+			siblings: []string{"docs", "Docs/"},
+			want:     []string{"Docs/"},
+		},
+		{
+			name:     "ranked best first",
+			missing:  "README.md",
+			siblings: []string{"README.txt", "readme.md", "READMEE.md"},
+			want:     []string{"readme.md", "README.txt", "READMEE.md"},
+		},
+		{
+			name:     "dotfiles do not share an empty stem",
+			missing:  ".env",
+			siblings: []string{".gitignore", ".npmrc", ".env.example"},
+			want:     []string{".env.example"},
+		},
+		{
+			name:     "no candidates",
+			missing:  "README.md",
+			siblings: []string{"main.go"},
+			want:     []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Candidates(tc.missing, tc.siblings)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Candidates(%q) = %v, want %v", tc.missing, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Package textdiff renders line-based unified diffs.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change.
+const contextLines = 3
+
+// maxCells bounds the LCS table size; larger inputs are diffed as a single
+// replacement hunk instead.
+const maxCells = 4 << 20
+
+type diffLine struct {
+	op   byte // ' ', '-', '+'
+	text string
+	a, b int // 1-based line numbers in from/to (0 when absent)
+}
+
+// Unified renders a unified diff from one text to another, labelling the
+// sides fromLabel and toLabel. The output is cut after maxLines lines (0
+// means no limit); the second return value reports whether that happened.
+func Unified(fromLabel, toLabel, from, to string, maxLines int) (string, bool) {
+	a := splitLines(from)
+	b := splitLines(to)
+	lines := diffLines(a, b)
+
+	var out []string
+	out = append(out, "--- "+fromLabel, "+++ "+toLabel)
+	for _, hunk := range groupHunks(lines, contextLines) {
+		out = append(out, hunkHeader(hunk))
+		for _, l := range hunk {
+			out = append(out, string(l.op)+l.text)
+		}
+	}
+
+	truncated := false
+	if maxLines > 0 && len(out) > maxLines {
+		omitted := len(out) - maxLines
+		out = append(out[:maxLines], fmt.Sprintf("... (%d more lines)", omitted))
+		truncated = true
+	}
+	return strings.Join(out, "\n"), truncated
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		if strings.HasSuffix(l, "\n") {
+			lines[i] = strings.TrimSuffix(l, "\n")
+		} else {
+			lines[i] = l + "\n\\ No newline at end of file"
+		}
+	}
+	return lines
+}
+
+// diffLines computes a line diff using the longest common subsequence of the
+// middle section after trimming a shared prefix and suffix.
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var out []diffLine
+	for i := 0; i < prefix; i++ {
+		out = append(out, diffLine{op: ' ', text: a[i], a: i + 1, b: i + 1})
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	for _, l := range diffMiddle(midA, midB) {
+		if l.a > 0 {
+			l.a += prefix
+		}
+		if l.b > 0 {
+			l.b += prefix
+		}
+		out = append(out, l)
+	}
+
+	for i := 0; i < suffix; i++ {
+		ai := len(a) - suffix + i
+		bi := len(b) - suffix + i
+		out = append(out, diffLine{op: ' ', text: a[ai], a: ai + 1, b: bi + 1})
+	}
+	return out
+}
+
+func diffMiddle(a, b []string) []diffLine {
+	var out []diffLine
+	if len(a)*len(b) > maxCells {
+		for i, l := range a {
+			out = append(out, diffLine{op: '-', text: l, a: i + 1})
+		}
+		for j, l := range b {
+			out = append(out, diffLine{op: '+', text: l, b: j + 1})
+		}
+		return out
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, diffLine{op: ' ', text: a[i], a: i + 1, b: j + 1})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, diffLine{op: '-', text: a[i], a: i + 1})
+			i++
+		default:
+			out = append(out, diffLine{op: '+', text: b[j], b: j + 1})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, diffLine{op: '-', text: a[i], a: i + 1})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, diffLine{op: '+', text: b[j], b: j + 1})
+	}
+	return out
+}
+
+// groupHunks splits diff lines into hunks, keeping up to context unchanged
+// lines around each change and merging hunks whose context overlaps.
+func groupHunks(lines []diffLine, context int) [][]diffLine {
+	var hunks [][]diffLine
+	start, end := -1, -1
+	for idx, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		lo := idx - context
+		if lo < 0 {
+			lo = 0
+		}
+		hi := idx + context + 1
+		if hi > len(lines) {
+			hi = len(lines)
+		}
+		if start >= 0 && lo <= end {
+			end = hi
+			continue
+		}
+		if start >= 0 {
+			hunks = append(hunks, lines[start:end])
+		}
+		start, end = lo, hi
+	}
+	if start >= 0 {
+		hunks = append(hunks, lines[start:end])
+	}
+	return hunks
+}
+
+func hunkHeader(hunk []diffLine) string {
+	aStart, aCount, bStart, bCount := 0, 0, 0, 0
+	for _, l := range hunk {
+		if l.op != '+' {
+			if aStart == 0 {
+				aStart = l.a
+			}
+			aCount++
+		}
+		if l.op != '-' {
+			if bStart == 0 {
+				bStart = l.b
+			}
+			bCount++
+		}
+	}
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+}
+
+// hunkRange formats a "start,count" range. A side with no lines in the hunk
+// only occurs when that side is empty, so its start is 0.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,33 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedHunks(t *testing.T) {
+	got, truncated := Unified("old", "new", "one\ntwo\nthree\n", "one\n2\nthree\nfour", 0)
+	want := "--- old\n+++ new\n@@ -1,3 +1,4 @@\n one\n-two\n+2\n three\n+four\n\\ No newline at end of file"
+	if truncated || got != want {
+		t.Fatalf("unexpected diff (truncated=%v):\n%s\nwant:\n%s", truncated, got, want)
+	}
+}
+
+func TestUnifiedTruncates(t *testing.T) {
+	var expected, actual string
+	for i := 0; i < 50; i++ {
+		expected += "a\n"
+		actual += "b\n"
+	}
+	diff, truncated := Unified("expected", "actual", expected, actual, 10)
+	if !truncated {
+		t.Fatalf("expected truncation")
+	}
+	lines := strings.Split(diff, "\n")
+	if len(lines) != 11 {
+		t.Fatalf("expected 11 lines, got %d", len(lines))
+	}
+	if lines[10] != "... (93 more lines)" {
+		t.Fatalf("unexpected trailer: %q", lines[10])
+	}
+}
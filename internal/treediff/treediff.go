@@ -0,0 +1,159 @@
+// Package treediff compares two walked trees, limited to the entries and
+// attributes a schema constrains.
+package treediff
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Change kinds.
+const (
+	KindAdded   = "added"
+	KindRemoved = "removed"
+	KindChanged = "changed"
+)
+
+// Change is one entry that differs between the trees. Details say what
+// changed for KindChanged.
+type Change struct {
+	Path    string   `json:"path"`
+	Kind    string   `json:"kind"`
+	Details []string `json:"details,omitempty"`
+}
+
+// attributes are the file attributes compared when a schema constrains
+// them, in report order.
+var attributes = []string{"symlink", "mode", "size", "sha256", "content"}
+
+// Diff compares instances a and b (as produced by fswalk.WalkWithSchema)
+// through schema: only entries the schema names or matches with a glob are
+// compared, and files only on the attributes their schema constrains, plus
+// symlink targets. With all, every entry and recorded attribute is compared.
+// Changes are ordered by path.
+func Diff(schema, a, b map[string]any, all bool) []Change {
+	d := &differ{all: all}
+	d.dir("", schema, a, b)
+	sort.SliceStable(d.changes, func(i, j int) bool { return d.changes[i].Path < d.changes[j].Path })
+	return d.changes
+}
+
+type differ struct {
+	all     bool
+	changes []Change
+}
+
+func (d *differ) dir(path string, schema, a, b map[string]any) {
+	for _, name := range unionKeys(a, b) {
+		entrySchema, covered := lookup(schema, name)
+		if !covered && !d.all {
+			continue
+		}
+		before, inA := a[name]
+		after, inB := b[name]
+		entryPath := path + name
+		switch {
+		case !inA:
+			d.changes = append(d.changes, Change{Path: entryPath, Kind: KindAdded})
+		case !inB:
+			d.changes = append(d.changes, Change{Path: entryPath, Kind: KindRemoved})
+		case strings.HasSuffix(name, "/"):
+			childA, _ := before.(map[string]any)
+			childB, _ := after.(map[string]any)
+			d.dir(entryPath, entrySchema, childA, childB)
+		default:
+			if details := d.file(entrySchema, before, after); len(details) > 0 {
+				d.changes = append(d.changes, Change{Path: entryPath, Kind: KindChanged, Details: details})
+			}
+		}
+	}
+}
+
+// file compares two instance file values: true, an attribute map, or a
+// {symlink: target} map.
+func (d *differ) file(schema map[string]any, before, after any) []string {
+	attrsA, _ := before.(map[string]any)
+	attrsB, _ := after.(map[string]any)
+	_, linkA := attrsA["symlink"]
+	_, linkB := attrsB["symlink"]
+	switch {
+	case linkA && !linkB:
+		return []string{"symlink replaced by a file"}
+	case !linkA && linkB:
+		return []string{"file replaced by a symlink"}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+	var details []string
+	for _, attr := range attributes {
+		if _, constrained := props[attr]; !constrained && !d.all && attr != "symlink" {
+			continue
+		}
+		valueA, inA := attrsA[attr]
+		valueB, inB := attrsB[attr]
+		if !inA || !inB || reflect.DeepEqual(valueA, valueB) {
+			continue
+		}
+		switch attr {
+		case "content":
+			details = append(details, "content differs")
+		case "sha256":
+			details = append(details, fmt.Sprintf("sha256 %s -> %s", shortHash(valueA), shortHash(valueB)))
+		default:
+			details = append(details, fmt.Sprintf("%s %v -> %v", attr, valueA, valueB))
+		}
+	}
+	return details
+}
+
+// lookup returns the schema of name in a directory schema and whether the
+// schema names or matches it.
+func lookup(schema map[string]any, name string) (map[string]any, bool) {
+	if props, ok := schema["properties"].(map[string]any); ok {
+		if entry, ok := props[name]; ok {
+			entrySchema, _ := entry.(map[string]any)
+			return entrySchema, true
+		}
+	}
+	patterns, _ := schema["patternProperties"].(map[string]any)
+	keys := make([]string, 0, len(patterns))
+	for pattern := range patterns {
+		keys = append(keys, pattern)
+	}
+	sort.Strings(keys)
+	for _, pattern := range keys {
+		re, err := regexp.Compile(pattern)
+		if err != nil || !re.MatchString(name) {
+			continue
+		}
+		entrySchema, _ := patterns[pattern].(map[string]any)
+		return entrySchema, true
+	}
+	return nil, false
+}
+
+func shortHash(value any) string {
+	s := fmt.Sprint(value)
+	if len(s) > 12 {
+		return s[:12]
+	}
+	return s
+}
+
+func unionKeys(a, b map[string]any) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, m := range []map[string]any{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				out = append(out, key)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,63 @@
+package treediff
+
+import (
+	"reflect"
+	"testing"
+
+	"dirschema/internal/expand"
+)
+
+func TestDiffThroughSchema(t *testing.T) {
+	schema, err := expand.ExpandDSL(map[string]any{
+		"README.md": true,
+		"app.bin":   map[string]any{"size": map[string]any{"min": 1}},
+		"link":      true,
+		"src/": map[string]any{
+			"*.go": map[string]any{"sha256": "x"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ExpandDSL: %v", err)
+	}
+	a := map[string]any{
+		"README.md": map[string]any{"size": int64(5), "sha256": "aaa"},
+		"app.bin":   map[string]any{"size": int64(10), "sha256": "bbb"},
+		"link":      map[string]any{"symlink": "README.md"},
+		"notes.txt": true,
+		"src/": map[string]any{
+			"main.go": map[string]any{"size": int64(1), "sha256": "ccc"},
+			"old.go":  map[string]any{"size": int64(1), "sha256": "ddd"},
+		},
+	}
+	b := map[string]any{
+		// Size and hash differ, but the schema only asks for existence.
+		"README.md": map[string]any{"size": int64(6), "sha256": "eee"},
+		"app.bin":   map[string]any{"size": int64(12), "sha256": "bbb"},
+		"link":      map[string]any{"symlink": "app.bin"},
+		"src/": map[string]any{
+			"main.go": map[string]any{"size": int64(1), "sha256": "fff"},
+			"new.go":  map[string]any{"size": int64(1), "sha256": "ddd"},
+		},
+	}
+
+	got := Diff(schema, a, b, false)
+	want := []Change{
+		{Path: "app.bin", Kind: KindChanged, Details: []string{"size 10 -> 12"}},
+		{Path: "link", Kind: KindChanged, Details: []string{"symlink README.md -> app.bin"}},
+		{Path: "src/main.go", Kind: KindChanged, Details: []string{"sha256 ccc -> fff"}},
+		{Path: "src/new.go", Kind: KindAdded},
+		{Path: "src/old.go", Kind: KindRemoved},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff mismatch:\ngot:  %+v\nwant: %+v", got, want)
+	}
+
+	all := Diff(schema, a, b, true)
+	paths := map[string]bool{}
+	for _, change := range all {
+		paths[change.Path] = true
+	}
+	if !paths["README.md"] || !paths["notes.txt"] {
+		t.Fatalf("expected --all to compare unmentioned entries and attributes, got %+v", all)
+	}
+}
@@ -0,0 +1,73 @@
+package validate
+
+import (
+	"strings"
+
+	"dirschema/internal/textdiff"
+)
+
+// MaxDiffLines bounds the number of lines kept in a content diff attached to
+// an error's details. Longer diffs are cut and flagged as truncated.
+const MaxDiffLines = 200
+
+// attachDetails fills Item.Details with the expected value from the schema
+// and the actual value from the instance for const violations on file
+// attributes (sha256, size, content, symlink).
+//
+// Content mismatches carry a unified diff instead of the raw values, since
+// the values themselves can be large.
+func attachDetails(items []Item, schema map[string]any, instance map[string]any) {
+	for i := range items {
+		if items[i].Keyword != "const" {
+			continue
+		}
+		attr := lastPointerSegment(items[i].InstancePath)
+		switch attr {
+		case "sha256", "size", "content", "symlink":
+		default:
+			continue
+		}
+
+		// The schema path points at the const keyword itself, so it resolves
+		// directly to the expected value.
+		expected := resolveJSONPointer(schema, extractFragment(items[i].SchemaPath))
+		if expected == nil {
+			continue
+		}
+		actual := resolveJSONPointer(instance, items[i].InstancePath)
+		if actual == nil {
+			continue
+		}
+
+		if attr == "content" {
+			expectedStr, eok := expected.(string)
+			actualStr, aok := actual.(string)
+			if eok && aok {
+				diff, truncated := textdiff.Unified("expected", "actual", expectedStr, actualStr, MaxDiffLines)
+				details := map[string]any{"diff": diff}
+				if truncated {
+					details["truncated"] = true
+				}
+				items[i].Details = details
+				continue
+			}
+		}
+
+		items[i].Details = map[string]any{
+			"expected": expected,
+			"actual":   actual,
+		}
+	}
+}
+
+// lastPointerSegment returns the final, unescaped segment of a JSON Pointer.
+func lastPointerSegment(pointer string) string {
+	idx := strings.LastIndex(pointer, "/")
+	if idx < 0 {
+		return ""
+	}
+	part := pointer[idx+1:]
+	part = strings.ReplaceAll(part, "~1", "/")
+	part = strings.ReplaceAll(part, "~0", "~")
+	return part
+}
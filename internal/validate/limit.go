@@ -0,0 +1,74 @@
+package validate
+
+// Limit keeps at most n items and records how many were dropped in
+// Truncated. Validity and the per-severity counts still describe the full
+// result. A non-positive n keeps everything.
+func (r *Result) Limit(n int) {
+	if n <= 0 || len(r.Errors) <= n {
+		return
+	}
+	r.Truncated += len(r.Errors) - n
+	r.Errors = r.Errors[:n]
+}
+
+// ShallowSchema returns a copy of a directory schema that keeps only the
+// constraints on the directory's own entry names: required names, glob
+// presence, additionalProperties=false and severity annotations. Child
+// schemas are replaced by permissive ones and other keywords are dropped, so
+// the result can be checked against a one-level walk before paying for a
+// full one without reporting anything the full schema would not. A child
+// that declares a symlink keeps an unconstrained symlink property, the hint
+// the walker needs to record the link rather than follow it.
+func ShallowSchema(schema map[string]any) map[string]any {
+	out := map[string]any{}
+	for key, value := range schema {
+		switch key {
+		case "type", "required", "propertyNames", SeverityKeyword:
+			out[key] = value
+		case "additionalProperties":
+			if b, ok := value.(bool); ok {
+				out[key] = b
+			}
+		case "properties", "patternProperties":
+			children, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			shallow := make(map[string]any, len(children))
+			for name, child := range children {
+				entry := map[string]any{}
+				if declaresSymlink(child) {
+					entry["properties"] = map[string]any{"symlink": map[string]any{}}
+				}
+				if sev, ok := annotatedSeverity(child); ok {
+					entry[SeverityKeyword] = string(sev)
+				}
+				shallow[name] = entry
+			}
+			out[key] = shallow
+		case "allOf":
+			entries, ok := value.([]any)
+			if !ok {
+				continue
+			}
+			var kept []any
+			for _, entry := range entries {
+				obj, ok := entry.(map[string]any)
+				if ok && extractGlobPresencePattern(obj["not"]) != "" {
+					kept = append(kept, entry)
+				}
+			}
+			if len(kept) > 0 {
+				out[key] = kept
+			}
+		}
+	}
+	return out
+}
+
+func declaresSymlink(schema any) bool {
+	obj, _ := schema.(map[string]any)
+	props, _ := obj["properties"].(map[string]any)
+	_, ok := props["symlink"]
+	return ok
+}
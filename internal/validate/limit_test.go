@@ -0,0 +1,116 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"dirschema/internal/fswalk"
+)
+
+func TestResultLimit(t *testing.T) {
+	res := Result{Errors: []Item{{InstancePath: "/a"}, {InstancePath: "/b"}, {InstancePath: "/c"}}}
+	res.tally()
+
+	res.Limit(0)
+	if len(res.Errors) != 3 || res.Truncated != 0 {
+		t.Fatalf("limit 0 should keep everything, got %+v", res)
+	}
+
+	res.Limit(2)
+	if len(res.Errors) != 2 || res.Truncated != 1 {
+		t.Fatalf("expected 2 items and 1 truncated, got %+v", res)
+	}
+	if res.ErrorCount != 3 || res.Valid {
+		t.Fatalf("counts should describe the full result, got %+v", res)
+	}
+}
+
+func TestShallowSchema(t *testing.T) {
+	existenceSchema := map[string]any{
+		"oneOf": []any{
+			map[string]any{"const": true},
+			map[string]any{"type": "object"},
+		},
+	}
+	globPresence := map[string]any{
+		"not": map[string]any{
+			"propertyNames": map[string]any{
+				"not": map[string]any{"pattern": "^.*\\.md$"},
+			},
+		},
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"src/": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"main.go": existenceSchema},
+				"required":   []any{"main.go"},
+			},
+			"NOTES": map[string]any{
+				"oneOf":         existenceSchema["oneOf"],
+				SeverityKeyword: "warning",
+			},
+		},
+		"patternProperties": map[string]any{"^.*\\.md$": existenceSchema},
+		"required":          []any{"NOTES", "src/"},
+		"allOf": []any{
+			globPresence,
+			map[string]any{"properties": map[string]any{"src/": map[string]any{"required": []any{"x"}}}},
+		},
+	}
+
+	got := ShallowSchema(schema)
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"src/":  map[string]any{},
+			"NOTES": map[string]any{SeverityKeyword: "warning"},
+		},
+		"patternProperties": map[string]any{"^.*\\.md$": map[string]any{}},
+		"required":          []any{"NOTES", "src/"},
+		"allOf":             []any{globPresence},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ShallowSchema mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestShallowSchemaKeepsSymlinkHint(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	root := t.TempDir()
+	if err := os.Symlink("missing", filepath.Join(root, "link")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"link": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"symlink": map[string]any{"const": "missing"}},
+				"required":   []any{"symlink"},
+			},
+		},
+		"required": []any{"link"},
+	}
+
+	// A declared dangling symlink is recorded, not followed, by the shallow
+	// walk just as by the full one.
+	shallow := ShallowSchema(schema)
+	inst, err := fswalk.WalkWithSchema(root, fswalk.Options{SymlinkPolicy: fswalk.SymlinkRecord, MaxDepth: 1}, shallow)
+	if err != nil {
+		t.Fatalf("WalkWithSchema: %v", err)
+	}
+	res, err := Validate(shallow, inst)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if !res.Valid {
+		t.Fatalf("expected valid shallow result, got %+v", res.Errors)
+	}
+}
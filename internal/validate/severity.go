@@ -0,0 +1,186 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity classifies a validation item. Only errors make a result invalid.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// SeverityKeyword is the schema annotation that sets the severity of
+// violations at and below the annotated entry.
+const SeverityKeyword = "x-dirschema-severity"
+
+// PromoteWarnings turns every warning into an error and recomputes the
+// result's validity, for pipelines run with --warnings-as-errors.
+func (r *Result) PromoteWarnings() {
+	for i := range r.Errors {
+		if r.Errors[i].Severity == SeverityWarning {
+			r.Errors[i].Severity = SeverityError
+		}
+	}
+	r.tally()
+}
+
+// applySeverity assigns a severity to each item from the innermost
+// x-dirschema-severity annotation along its schema path.
+//
+// A required violation is judged by the annotations on the missing entries
+// themselves; when those disagree, the item is split into one item per
+// severity so warnings never hide inside an error (or vice versa).
+func applySeverity(items []Item, schema map[string]any, instance map[string]any) []Item {
+	out := make([]Item, 0, len(items))
+	for _, item := range items {
+		fragment := extractFragment(item.SchemaPath)
+		switch item.Keyword {
+		case "required":
+			out = append(out, splitRequiredBySeverity(item, schema, instance, fragment)...)
+			continue
+		case "glob-presence":
+			item.Severity = globPresenceSeverity(schema, fragment)
+		default:
+			item.Severity = severityAlong(schema, fragment)
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// severityAlong walks a JSON Pointer through the schema and returns the
+// innermost severity annotation seen, defaulting to error.
+func severityAlong(schema map[string]any, pointer string) Severity {
+	sev := SeverityError
+	var current any = schema
+	if s, ok := annotatedSeverity(current); ok {
+		sev = s
+	}
+	pointer = strings.TrimPrefix(pointer, "/")
+	if pointer == "" {
+		return sev
+	}
+	for _, part := range strings.Split(pointer, "/") {
+		next, ok := pointerStep(current, part)
+		if !ok {
+			break
+		}
+		current = next
+		if s, ok := annotatedSeverity(current); ok {
+			sev = s
+		}
+	}
+	return sev
+}
+
+func annotatedSeverity(node any) (Severity, bool) {
+	obj, ok := node.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	switch obj[SeverityKeyword] {
+	case string(SeverityWarning):
+		return SeverityWarning, true
+	case string(SeverityError):
+		return SeverityError, true
+	}
+	return "", false
+}
+
+// globPresenceSeverity resolves the severity of a glob-presence item from the
+// annotation on the pattern's own schema, falling back to the directory's.
+func globPresenceSeverity(schema map[string]any, fragment string) Severity {
+	idx := strings.LastIndex(fragment, "/allOf/")
+	if idx < 0 {
+		return severityAlong(schema, fragment)
+	}
+	dirPointer := fragment[:idx]
+	sev := severityAlong(schema, dirPointer)
+	pattern := extractGlobPresencePattern(resolveJSONPointer(schema, fragment))
+	dir, _ := resolveJSONPointer(schema, dirPointer).(map[string]any)
+	patterns, _ := dir["patternProperties"].(map[string]any)
+	if s, ok := annotatedSeverity(patterns[pattern]); ok {
+		sev = s
+	}
+	return sev
+}
+
+func splitRequiredBySeverity(item Item, schema, instance map[string]any, fragment string) []Item {
+	dirPointer := strings.TrimSuffix(fragment, "/required")
+	inherited := severityAlong(schema, dirPointer)
+	dir, _ := resolveJSONPointer(schema, dirPointer).(map[string]any)
+	props, _ := dir["properties"].(map[string]any)
+
+	names := missingRequired(dir, resolveJSONPointer(instance, item.InstancePath))
+	if len(names) == 0 {
+		item.Severity = inherited
+		return []Item{item}
+	}
+
+	bySeverity := map[Severity][]string{}
+	for _, name := range names {
+		sev := inherited
+		if s, ok := annotatedSeverity(props[name]); ok {
+			sev = s
+		}
+		bySeverity[sev] = append(bySeverity[sev], name)
+	}
+	if len(bySeverity) == 1 {
+		for sev := range bySeverity {
+			item.Severity = sev
+		}
+		return []Item{item}
+	}
+
+	var out []Item
+	for _, sev := range []Severity{SeverityError, SeverityWarning} {
+		group := bySeverity[sev]
+		split := item
+		split.Severity = sev
+		split.Message = formatMissing(group)
+		split.Suggestions = nil
+		for _, s := range item.Suggestions {
+			for _, name := range group {
+				if s.Missing == name {
+					split.Suggestions = append(split.Suggestions, s)
+				}
+			}
+		}
+		out = append(out, split)
+	}
+	return out
+}
+
+// missingRequired lists the names in the schema's required array that are
+// absent from the instance object, in schema order.
+func missingRequired(schema map[string]any, instance any) []string {
+	obj, ok := instance.(map[string]any)
+	if !ok {
+		return nil
+	}
+	required, _ := schema["required"].([]any)
+	var names []string
+	for _, raw := range required {
+		name, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		if _, present := obj[name]; !present {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// formatMissing renders names the way the validator reports them.
+func formatMissing(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + strings.ReplaceAll(name, "'", `\'`) + "'"
+	}
+	return fmt.Sprintf("missing properties: %s", strings.Join(quoted, ", "))
+}
@@ -0,0 +1,63 @@
+package validate
+
+import (
+	"sort"
+	"strings"
+
+	"dirschema/internal/suggest"
+)
+
+// Suggestion pairs a missing required entry with sibling names in the
+// instance that look like a misspelling of it.
+type Suggestion struct {
+	Missing    string   `json:"missing"`
+	Candidates []string `json:"candidates"`
+}
+
+// attachSuggestions fills Item.Suggestions for required violations whose
+// missing names have a close match among the actual siblings.
+//
+// Siblings that the schema already names as literal properties are skipped;
+// they are expected entries, not misspellings.
+func attachSuggestions(items []Item, schema map[string]any, instance map[string]any) {
+	for i := range items {
+		if items[i].Keyword != "required" {
+			continue
+		}
+		fragment := extractFragment(items[i].SchemaPath)
+		required, ok := resolveJSONPointer(schema, fragment).([]any)
+		if !ok {
+			continue
+		}
+		dir, ok := resolveJSONPointer(instance, items[i].InstancePath).(map[string]any)
+		if !ok {
+			continue
+		}
+
+		var known map[string]any
+		if parent, ok := resolveJSONPointer(schema, strings.TrimSuffix(fragment, "/required")).(map[string]any); ok {
+			known, _ = parent["properties"].(map[string]any)
+		}
+		siblings := make([]string, 0, len(dir))
+		for name := range dir {
+			if _, ok := known[name]; ok {
+				continue
+			}
+			siblings = append(siblings, name)
+		}
+		sort.Strings(siblings)
+
+		for _, raw := range required {
+			name, ok := raw.(string)
+			if !ok {
+				continue
+			}
+			if _, present := dir[name]; present {
+				continue
+			}
+			if candidates := suggest.Candidates(name, siblings); len(candidates) > 0 {
+				items[i].Suggestions = append(items[i].Suggestions, Suggestion{Missing: name, Candidates: candidates})
+			}
+		}
+	}
+}
@@ -11,16 +11,50 @@ import (
 )
 
 type Result struct {
-	Valid  bool   `json:"valid"`
-	Errors []Item `json:"errors,omitempty"`
+	Valid        bool   `json:"valid"`
+	ErrorCount   int    `json:"errorCount"`
+	WarningCount int    `json:"warningCount"`
+	Truncated    int    `json:"truncated,omitempty"`
+	Errors       []Item `json:"errors,omitempty"`
 }
 
 type Item struct {
-	InstancePath string      `json:"instancePath"`
-	SchemaPath   string      `json:"schemaPath"`
-	Keyword      string      `json:"keyword"`
-	Message      string      `json:"message"`
-	Details      interface{} `json:"details,omitempty"`
+	InstancePath string       `json:"instancePath"`
+	SchemaPath   string       `json:"schemaPath"`
+	Keyword      string       `json:"keyword"`
+	Message      string       `json:"message"`
+	Details      interface{}  `json:"details,omitempty"`
+	Suggestions  []Suggestion `json:"suggestions,omitempty"`
+	Severity     Severity     `json:"severity,omitempty"`
+}
+
+// Retain keeps only the items for which keep returns true and recomputes
+// the result's validity.
+func (r *Result) Retain(keep func(Item) bool) {
+	kept := r.Errors[:0]
+	for _, item := range r.Errors {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	r.Errors = kept
+	r.tally()
+}
+
+// tally recomputes Valid and the per-severity counts from Errors.
+func (r *Result) tally() {
+	r.ErrorCount, r.WarningCount = 0, 0
+	for _, item := range r.Errors {
+		if item.Severity == SeverityWarning {
+			r.WarningCount++
+		} else {
+			r.ErrorCount++
+		}
+	}
+	r.Valid = r.ErrorCount == 0
 }
 
 func Validate(schema map[string]any, instance map[string]any) (Result, error) {
@@ -46,7 +80,11 @@ func Validate(schema map[string]any, instance map[string]any) (Result, error) {
 		}
 		items := flattenErrors(ve)
 		rewriteGlobPresenceErrors(items, schema)
-		return Result{Valid: false, Errors: items}, nil
+		attachDetails(items, schema, instance)
+		attachSuggestions(items, schema, instance)
+		result := Result{Errors: applySeverity(items, schema, instance)}
+		result.tally()
+		return result, nil
 	}
 
 	return Result{Valid: true}, nil
@@ -171,37 +209,43 @@ func resolveJSONPointer(schema map[string]any, pointer string) any {
 
 	var current any = schema
 	for _, part := range parts {
-		// Decode JSON Pointer escapes: ~1 → /, ~0 → ~
-		part = strings.ReplaceAll(part, "~1", "/")
-		part = strings.ReplaceAll(part, "~0", "~")
-
-		switch v := current.(type) {
-		case map[string]any:
-			next, ok := v[part]
-			if !ok {
-				return nil
-			}
-			current = next
-		case []any:
-			// Array index
-			idx := 0
-			for _, c := range part {
-				if c < '0' || c > '9' {
-					return nil
-				}
-				idx = idx*10 + int(c-'0')
-			}
-			if idx >= len(v) {
-				return nil
-			}
-			current = v[idx]
-		default:
+		next, ok := pointerStep(current, part)
+		if !ok {
 			return nil
 		}
+		current = next
 	}
 	return current
 }
 
+// pointerStep follows a single, still-escaped JSON Pointer segment.
+func pointerStep(current any, part string) (any, bool) {
+	// Decode JSON Pointer escapes: ~1 → /, ~0 → ~
+	part = strings.ReplaceAll(part, "~1", "/")
+	part = strings.ReplaceAll(part, "~0", "~")
+
+	switch v := current.(type) {
+	case map[string]any:
+		next, ok := v[part]
+		return next, ok
+	case []any:
+		// Array index
+		idx := 0
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return nil, false
+			}
+			idx = idx*10 + int(c-'0')
+		}
+		if idx >= len(v) {
+			return nil, false
+		}
+		return v[idx], true
+	default:
+		return nil, false
+	}
+}
+
 // extractGlobPresencePattern checks if a sub-schema has the shape:
 //
 //	{"propertyNames": {"not": {"pattern": R}}}
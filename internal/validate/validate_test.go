@@ -237,3 +237,188 @@ func TestValidateJSONRoundTrip(t *testing.T) {
 		t.Fatalf("round trip mismatch")
 	}
 }
+
+func TestValidateConstDetails(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"src/": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"main.go": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"sha256": map[string]any{"const": "abc"},
+							"size":   map[string]any{"const": 3},
+						},
+						"required": []any{"sha256", "size"},
+					},
+					"link": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"symlink": map[string]any{"const": "main.go"},
+						},
+						"required": []any{"symlink"},
+					},
+				},
+				"required": []any{"link", "main.go"},
+			},
+		},
+		"required": []any{"src/"},
+	}
+	instance := map[string]any{
+		"src/": map[string]any{
+			"main.go": map[string]any{"sha256": "def", "size": int64(5)},
+			"link":    map[string]any{"symlink": "other.go"},
+		},
+	}
+
+	res, err := Validate(schema, instance)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	got := map[string]any{}
+	for _, e := range res.Errors {
+		got[e.InstancePath] = e.Details
+	}
+	want := map[string]any{
+		"/src~1/link/symlink":   map[string]any{"expected": "main.go", "actual": "other.go"},
+		"/src~1/main.go/sha256": map[string]any{"expected": "abc", "actual": "def"},
+		"/src~1/main.go/size":   map[string]any{"expected": 3, "actual": int64(5)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("details mismatch:\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestValidateContentDiffDetails(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"a.txt": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"content": map[string]any{"const": "one\ntwo\nthree\n"},
+				},
+				"required": []any{"content"},
+			},
+		},
+		"required": []any{"a.txt"},
+	}
+	instance := map[string]any{
+		"a.txt": map[string]any{"content": "one\n2\nthree\n"},
+	}
+
+	res, err := Validate(schema, instance)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(res.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", res.Errors)
+	}
+
+	want := map[string]any{
+		"diff": "--- expected\n+++ actual\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three",
+	}
+	if !reflect.DeepEqual(res.Errors[0].Details, want) {
+		t.Fatalf("details mismatch:\ngot:  %#v\nwant: %#v", res.Errors[0].Details, want)
+	}
+}
+
+func TestValidateSuggestsMisspelledSiblings(t *testing.T) {
+	existenceSchema := map[string]any{
+		"oneOf": []any{
+			map[string]any{"const": true},
+			map[string]any{"type": "object"},
+		},
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"LICENSE":   existenceSchema,
+			"README.md": existenceSchema,
+			"main.go":   existenceSchema,
+		},
+		"required": []any{"LICENSE", "README.md", "main.go"},
+	}
+	instance := map[string]any{
+		"Readme.md": true,
+		"main.go":   true,
+	}
+
+	res, err := Validate(schema, instance)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(res.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", res.Errors)
+	}
+
+	want := []Suggestion{{Missing: "README.md", Candidates: []string{"Readme.md"}}}
+	if !reflect.DeepEqual(res.Errors[0].Suggestions, want) {
+		t.Fatalf("suggestions = %#v, want %#v", res.Errors[0].Suggestions, want)
+	}
+}
+
+func TestValidateSeverity(t *testing.T) {
+	existenceSchema := map[string]any{
+		"oneOf": []any{
+			map[string]any{"const": true},
+			map[string]any{"type": "object"},
+		},
+	}
+	warned := map[string]any{
+		"oneOf":         existenceSchema["oneOf"],
+		SeverityKeyword: "warning",
+	}
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"LICENSE":   existenceSchema,
+			"NOTES.md":  warned,
+			"README.md": existenceSchema,
+			"legacy/": map[string]any{
+				"type":          "object",
+				"properties":    map[string]any{"old.txt": existenceSchema},
+				"required":      []any{"old.txt"},
+				SeverityKeyword: "warning",
+			},
+		},
+		"required": []any{"LICENSE", "NOTES.md", "README.md", "legacy/"},
+	}
+
+	res, err := Validate(schema, map[string]any{"README.md": true, "legacy/": map[string]any{}})
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if res.Valid || res.ErrorCount != 1 || res.WarningCount != 2 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	got := []string{}
+	for _, e := range res.Errors {
+		got = append(got, string(e.Severity)+" "+e.InstancePath+" "+e.Message)
+	}
+	want := []string{
+		"error  missing properties: 'LICENSE'",
+		"warning  missing properties: 'NOTES.md'",
+		"warning /legacy~1 missing properties: 'old.txt'",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("items = %q, want %q", got, want)
+	}
+
+	onlyWarnings, err := Validate(schema, map[string]any{"LICENSE": true, "README.md": true, "legacy/": map[string]any{}})
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if !onlyWarnings.Valid || onlyWarnings.WarningCount != 2 {
+		t.Fatalf("expected valid result with warnings, got %+v", onlyWarnings)
+	}
+
+	onlyWarnings.PromoteWarnings()
+	if onlyWarnings.Valid || onlyWarnings.ErrorCount != 2 || onlyWarnings.WarningCount != 0 {
+		t.Fatalf("expected promoted warnings to invalidate, got %+v", onlyWarnings)
+	}
+}